@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"huh/internal/config"
+	"huh/internal/llm"
+	"huh/internal/ui"
+	"huh/internal/usercontext"
+
+	"github.com/spf13/cobra"
+)
+
+// doctorTimeout bounds how long doctor waits for each provider.
+const doctorTimeout = 10 * time.Second
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the config, providers and environment",
+	Long: "Check that the config file loads, the providers are set up, reachable and have " +
+		"their model, and that the clipboard and terminal work. Prints a line per check " +
+		"with a hint for the ones that fail.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		checks := runChecks(cmd.Context())
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(checks); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			printChecks(os.Stdout, checks)
+		}
+		for _, c := range checks {
+			if !c.OK {
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(askOnBadArgs(doctorCmd))
+}
+
+// check is the outcome of one thing doctor looks at.
+type check struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider,omitempty"` // For the checks of a provider
+	OK       bool   `json:"ok"`
+	Detail   string `json:"detail"`
+	Hint     string `json:"hint,omitempty"` // What to do about it, for failed checks
+}
+
+func pass(name, detail string) check {
+	return check{Name: name, OK: true, Detail: detail}
+}
+
+func fail(name, detail, hint string) check {
+	return check{Name: name, Detail: detail, Hint: hint}
+}
+
+func runChecks(ctx context.Context) []check {
+	checks := []check{checkConfig()}
+
+	provider := flagOrEnv(providerName, "HUH_PROVIDER")
+	if err := config.Override(provider, flagOrEnv(modelName, "HUH_MODEL")); err != nil {
+		c := fail("Provider", err.Error(), "Pick one of the configured providers.")
+		c.Provider = provider
+		checks = append(checks, c)
+	}
+	for _, name := range providerChain() {
+		checks = append(checks, checkProvider(ctx, name)...)
+	}
+
+	checks = append(checks, checkClipboard(), checkTTY())
+	return append(checks, checkSystem()...)
+}
+
+func checkConfig() check {
+	path, err := config.GetConfigLocation()
+	if err != nil {
+		return fail("Config", err.Error(), "Set XDG_CONFIG_HOME or HOME.")
+	}
+	if configErr != nil {
+		return fail("Config", configErr.Error(), fmt.Sprintf("Fix %s, or move it away and huh writes a fresh default.", path))
+	}
+	if config.LiteralKeyExposed(path) {
+		return fail("Config", path+" holds an API key others can read",
+			fmt.Sprintf("Run chmod 600 %s, or move the key to ${VAR}, api_key_cmd or api_key_keyring.", path))
+	}
+	return pass("Config", path)
+}
+
+// providerChain lists the default provider and its fallbacks, in the
+// order they're tried.
+func providerChain() []string {
+	names := []string{config.AppConfig.DefaultProvider}
+	for _, fb := range config.AppConfig.Fallback {
+		if !slices.Contains(names, fb) {
+			names = append(names, fb)
+		}
+	}
+	return names
+}
+
+// keyTypes are the provider types that can't work without an api_key.
+var keyTypes = []string{"openai", "openrouter", "anthropic"}
+
+// checkProvider checks the named provider's config, that it answers and
+// that it has the configured model.
+func checkProvider(ctx context.Context, name string) []check {
+	var checks []check
+	add := func(c check) []check {
+		c.Provider = name
+		return append(checks, c)
+	}
+	role := "fallback"
+	if name == config.AppConfig.DefaultProvider {
+		role = "default"
+	}
+
+	cfg, ok := config.AppConfig.Providers[name]
+	if !ok {
+		return add(fail("Provider", role+", not configured", "Add it under providers in the config, or change default_provider."))
+	}
+	source := config.KeySource(cfg.Params)
+	if slices.Contains(keyTypes, cfg.Type) && source == "" {
+		return add(fail("Provider", role+", "+cfg.Type+", no API key set", fmt.Sprintf("Set api_key, api_key_cmd or api_key_keyring in the params of %s.", name)))
+	}
+	p, err := llm.NewProvider(name)
+	if err != nil {
+		return add(fail("Provider", err.Error(), "Check the provider's type and params in the config."))
+	}
+	detail := role + ", " + cfg.Type
+	switch source {
+	case "api_key":
+		detail += ", API key set"
+	case "api_key_cmd", "api_key_keyring":
+		detail += ", API key from " + source
+	}
+	checks = add(pass("Provider", detail))
+
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+	models, err := llm.Models(ctx, p)
+	switch {
+	case errors.Is(err, llm.ErrNoModelList):
+		return checks
+	case err != nil:
+		hint := ui.ErrorHint(err)
+		if hint == "" {
+			hint = "Check the provider's host or base_url."
+		}
+		return add(fail("Connection", err.Error(), hint))
+	}
+	checks = add(pass("Connection", "reachable"))
+
+	model := p.ModelName()
+	if !llm.HasModel(models, model) {
+		hint := fmt.Sprintf("Run huh models %s to pick one that is.", name)
+		if cfg.Type == "ollama" {
+			hint = fmt.Sprintf("Run ollama pull %s, or huh models %s to pick another one.", model, name)
+		}
+		return add(fail("Model", model+" is not available", hint))
+	}
+	return add(pass("Model", model))
+}
+
+func checkClipboard() check {
+	if runtime.GOOS != "linux" {
+		return pass("Clipboard", "built in")
+	}
+	tool := usercontext.DetectClipboard()
+	if tool == "unknown" {
+		hint := "Install xclip or xsel. --output and the shell integration work without one."
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			hint = "Install wl-clipboard. --output and the shell integration work without one."
+		}
+		return fail("Clipboard", "no clipboard tool found", hint)
+	}
+	return pass("Clipboard", tool)
+}
+
+// checkTTY checks /dev/tty, which the TUI reads keys from when stdin is a
+// pipe.
+func checkTTY() check {
+	f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fail("Terminal", "/dev/tty: "+err.Error(), "Piping into the TUI won't work here, use --print or -f instead.")
+	}
+	f.Close()
+	return pass("Terminal", "/dev/tty usable for piped input")
+}
+
+// checkSystem reports what huh tells the model about the system.
+func checkSystem() []check {
+	sys := usercontext.GetContext()
+	checks := []check{pass("Shell", sys.Shell)}
+
+	if sys.Distro == "" || sys.Distro == "linux (unknown)" {
+		checks = append(checks, fail("Distro", "unknown", "Set context.distro in the config, e.g. \"Arch Linux\"."))
+	} else {
+		checks = append(checks, pass("Distro", sys.DistroDescription()))
+	}
+
+	switch {
+	case sys.PackageMgr == "" || sys.PackageMgr == "unknown":
+		checks = append(checks, fail("Package manager", "none found", "Set context.package_mgr in the config."))
+	case len(sys.ExtraPackageMgrs) > 0:
+		checks = append(checks, pass("Package manager", fmt.Sprintf("%s (also %s)", sys.PackageMgr, strings.Join(sys.ExtraPackageMgrs, ", "))))
+	default:
+		checks = append(checks, pass("Package manager", sys.PackageMgr))
+	}
+	return checks
+}
+
+func printChecks(w io.Writer, checks []check) {
+	for _, c := range checks {
+		mark := "✓"
+		if !c.OK {
+			mark = "✗"
+		}
+		// The connection and model checks go under their provider
+		indent, name := "", c.Name
+		switch {
+		case c.Name == "Provider":
+			name += " " + c.Provider
+		case c.Provider != "":
+			indent = "  "
+		}
+		fmt.Fprintf(w, "%s%s %s: %s\n", indent, mark, name, c.Detail)
+		if c.Hint != "" {
+			fmt.Fprintf(w, "%s  %s\n", indent, c.Hint)
+		}
+	}
+}
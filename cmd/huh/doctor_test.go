@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"huh/internal/config"
+)
+
+func TestRunChecks(t *testing.T) {
+	savedConfig, savedErr := config.AppConfig, configErr
+	t.Cleanup(func() { config.AppConfig, configErr = savedConfig, savedErr })
+	t.Setenv("HUH_PROVIDER", "")
+	t.Setenv("HUH_MODEL", "")
+
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models":[{"name":"llama3:8b"}]}`)
+	}))
+	defer ollama.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	withOllama := func(host, model string) config.Config {
+		return config.Config{
+			DefaultProvider: "local",
+			Providers: map[string]config.ProviderConfig{
+				"local": {Type: "ollama", Params: map[string]string{"host": host, "model": model, "max_retries": "0"}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		noHome    bool
+		configErr error
+		config    config.Config
+		want      []string // Name and outcome of the config and provider checks
+	}{
+		{
+			name:   "missing config",
+			noHome: true,
+			config: withOllama(ollama.URL, "llama3:8b"),
+			want:   []string{"Config ✗", "Provider ✓", "Connection ✓", "Model ✓"},
+		},
+		{
+			name:      "broken config",
+			configErr: errors.New("yaml: line 3: did not find expected key"),
+			config:    withOllama(ollama.URL, "llama3:8b"),
+			want:      []string{"Config ✗", "Provider ✓", "Connection ✓", "Model ✓"},
+		},
+		{
+			name: "missing key",
+			config: config.Config{
+				DefaultProvider: "openai",
+				Providers:       map[string]config.ProviderConfig{"openai": {Type: "openai", Params: map[string]string{"model": "gpt-4o"}}},
+			},
+			want: []string{"Config ✓", "Provider ✗"},
+		},
+		{
+			name:   "unreachable provider",
+			config: withOllama(down.URL, "llama3:8b"),
+			want:   []string{"Config ✓", "Provider ✓", "Connection ✗"},
+		},
+		{
+			name:   "missing model",
+			config: withOllama(ollama.URL, "qwen3:8b"),
+			want:   []string{"Config ✓", "Provider ✓", "Connection ✓", "Model ✗"},
+		},
+		{
+			name: "missing fallback",
+			config: func() config.Config {
+				c := withOllama(ollama.URL, "llama3:8b")
+				c.Fallback = []string{"cloud"}
+				return c
+			}(),
+			want: []string{"Config ✓", "Provider ✓", "Connection ✓", "Model ✓", "Provider ✗"},
+		},
+		{
+			name:   "all OK",
+			config: withOllama(ollama.URL, "llama3:8b"),
+			want:   []string{"Config ✓", "Provider ✓", "Connection ✓", "Model ✓"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.noHome {
+				t.Setenv("XDG_CONFIG_HOME", "")
+				t.Setenv("HOME", "")
+			} else {
+				t.Setenv("XDG_CONFIG_HOME", dir)
+				if err := os.MkdirAll(filepath.Join(dir, "huh"), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, "huh", "config.yaml"), []byte("default_provider: local\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			config.AppConfig, configErr = tt.config, tt.configErr
+
+			var got []string
+			for _, c := range runChecks(context.Background()) {
+				switch c.Name {
+				case "Config", "Provider", "Connection", "Model":
+					mark := "✓"
+					if !c.OK {
+						mark = "✗"
+					}
+					got = append(got, c.Name+" "+mark)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("checks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"huh/internal/attach"
+	"huh/internal/usercontext"
+
+	"github.com/spf13/cobra"
+)
+
+var fixCommand string
+var fixExitCode int
+var fixStderr string
+
+var fixCmd = &cobra.Command{
+	Use:   "fix [note]",
+	Short: "Ask why the last command failed and how to fix it",
+	Long: "Ask why the last command failed and how to fix it.\n\n" +
+		"The command and its exit code come from the shell integration (huh init), " +
+		"or from --command and --exit-code. Without them the last entry of the shell's " +
+		"history file is used. Output piped to stdin or given with --stderr is attached too.",
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		command := strings.TrimSpace(fixCommand)
+		if command == "" {
+			shell := usercontext.GetContext().Shell
+			last, err := usercontext.LastCommand(shell)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: could not find the last command: %v\n", err)
+				fmt.Fprintln(os.Stderr, "Pass it with --command, or set up the shell integration with 'huh init'.")
+				os.Exit(1)
+			}
+			command = last
+		}
+
+		sess := startSession(cmd)
+		budget := sess.AttachmentBudget()
+		if fixStderr != "" {
+			// Stderr is what matters most, give it half
+			budget /= 2
+		}
+		attachedContent, contextInfo := readAttachments("Output", budget)
+		if fixStderr != "" {
+			content, stats, err := attach.ReadFile(fixStderr, budget)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not read %s: %v\n", fixStderr, err)
+			} else {
+				attachedContent = attachment("Stderr", content) + attachedContent
+				contextInfo = joinInfo(attach.Describe("Stderr", stats), contextInfo)
+			}
+		}
+
+		failed := command
+		if cmd.Flags().Changed("exit-code") {
+			failed += fmt.Sprintf("\n(exit code %d)", fixExitCode)
+		}
+		attachedContent = attachment("Failed command", failed) + attachedContent
+		contextInfo = joinInfo("Failed command", contextInfo)
+
+		question := "Why did the attached command fail, and what is the corrected command?"
+		if note := strings.Join(args, " "); note != "" {
+			question += " " + note
+		}
+		ask(sess, question, contextInfo, attachedContent)
+	},
+}
+
+func joinInfo(first, rest string) string {
+	if rest == "" {
+		return first
+	}
+	return first + ", " + rest
+}
+
+func init() {
+	fixCmd.Flags().StringVar(&fixCommand, "command", "", "the command that failed (default: last entry in the shell history)")
+	fixCmd.Flags().IntVar(&fixExitCode, "exit-code", 0, "exit code of the failed command")
+	fixCmd.Flags().StringVar(&fixStderr, "stderr", "", "file holding the command's error output")
+	rootCmd.AddCommand(fixCmd)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"huh/internal/history"
+	"huh/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var clearHistory bool
+
+var historyCmd = &cobra.Command{
+	Use:   "history [search]",
+	Short: "Browse past questions and answers",
+	Long: "Browse past questions and answers. Pick one to look at it again, copy its " +
+		"command or ask the question again. When not running in a terminal the " +
+		"matching entries are printed instead.",
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := history.DefaultStore()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if clearHistory {
+			if err := store.Clear(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("History cleared.")
+			return
+		}
+
+		entries, err := store.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+			os.Exit(1)
+		}
+		query := strings.Join(args, " ")
+
+		if printMode || jsonOutput || !stdoutIsTerminal() {
+			if err := printHistory(os.Stdout, history.Search(entries, query), jsonOutput); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		// Browsing works without a provider, it's set up once a question
+		// is asked again
+		model := newModel(startLazySession(cmd), "", "", "")
+		model.ShowHistory(entries, query)
+		runTUI(model)
+	},
+}
+
+func init() {
+	historyCmd.Flags().BoolVar(&clearHistory, "clear", false, "delete all history")
+	rootCmd.AddCommand(historyCmd)
+}
+
+func printHistory(w io.Writer, entries []history.Entry, asJSON bool) error {
+	if asJSON {
+		if entries == nil {
+			entries = []history.Entry{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s  %s\n", e.Time.Local().Format("2006-01-02 15:04"), e.Question)
+		for _, r := range e.Refinements {
+			fmt.Fprintf(w, "    > %s\n", r)
+		}
+		for _, c := range e.Commands {
+			fmt.Fprintf(w, "    %s\n", strings.ReplaceAll(c, "\n", "\n    "))
+		}
+	}
+	return nil
+}
+
+func loadHistory() ([]history.Entry, error) {
+	store, err := history.DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.Load()
+}
+
+// recordModel saves the suggestion the TUI ended on. Past answers that were
+// only looked at again aren't saved twice.
+func recordModel(m ui.Model) {
+	if m.Suggestion == "" || (m.FromHistory && m.Action == "") {
+		return
+	}
+	recordHistory(history.Entry{
+		Question:    m.Question,
+		Refinements: m.Refinements,
+		Attachments: splitInfo(m.ContextInfo),
+		Provider:    m.AnsweredBy,
+		Model:       m.AnsweredModel,
+		Answer:      m.Suggestion,
+		Commands:    m.RunnableCommands,
+		Action:      m.Action,
+	})
+}
+
+func recordHistory(e history.Entry) {
+	store, err := history.DefaultStore()
+	if err == nil {
+		err = store.Append(e)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save history: %v\n", err)
+	}
+}
+
+// splitInfo turns the TUI's "a.log, Stdin" context description back into
+// a list.
+func splitInfo(contextInfo string) []string {
+	if contextInfo == "" {
+		return nil
+	}
+	return strings.Split(contextInfo, ", ")
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+//go:embed shell
+var shellSnippets embed.FS
+
+var initCmd = &cobra.Command{
+	Use:       "init bash|zsh|fish",
+	Short:     "Print the shell integration snippet",
+	Long:      "Print a snippet that binds Ctrl-G to ask huh about the current line and replace it with the chosen command.",
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		b, err := shellSnippets.ReadFile("shell/huh." + args[0])
+		if err != nil {
+			return fmt.Errorf("unsupported shell %q", args[0])
+		}
+		_, err = cmd.OutOrStdout().Write(b)
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(askOnBadArgs(initCmd))
+}
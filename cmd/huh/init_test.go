@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitSnippets(t *testing.T) {
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+	})
+
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			var out bytes.Buffer
+			rootCmd.SetOut(&out)
+			rootCmd.SetArgs([]string{"init", shell})
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("huh init %s: %v", shell, err)
+			}
+
+			// The widget reads the chosen command back from --output and
+			// the TUI needs the terminal, whatever the prompt's stdin is
+			snippet := out.String()
+			for _, want := range []string{"huh --output", "</dev/tty", "huh fix --command"} {
+				if !strings.Contains(snippet, want) {
+					t.Errorf("snippet lacks %q", want)
+				}
+			}
+
+			// Check the syntax with the shell itself, where it's installed
+			path, err := exec.LookPath(shell)
+			if err != nil {
+				t.Skipf("%s not installed", shell)
+			}
+			check := exec.Command(path, "-n")
+			check.Stdin = strings.NewReader(snippet)
+			if msg, err := check.CombinedOutput(); err != nil {
+				t.Errorf("%s -n: %v: %s", shell, err, msg)
+			}
+		})
+	}
+}
+
+func TestBashFixLastCommand(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+	snippet, err := shellSnippets.ReadFile("shell/huh.bash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A huh that prints what the shell function passed it
+	dir := t.TempDir()
+	fake := "#!/bin/sh\nprintf 'huh'; printf ' [%s]' \"$@\"; echo\n"
+	if err := os.WriteFile(filepath.Join(dir, "huh"), []byte(fake), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "huh.bash"), snippet, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The huh fix line is left out of the history with ignorespace, and
+	// is in it otherwise
+	for _, histcontrol := range []string{"", "ignoredups", "ignorespace", "ignoreboth"} {
+		script := "HISTCONTROL=" + histcontrol + "\n" +
+			"source " + filepath.Join(dir, "huh.bash") + "\n" +
+			"false\n" +
+			" huh fix\n" +
+			"false\n" +
+			"huh fix\n"
+		cmd := exec.Command(bash, "--norc", "-i")
+		cmd.Stdin = strings.NewReader(script)
+		cmd.Env = append(os.Environ(), "PATH="+dir+":"+os.Getenv("PATH"), "HISTFILE=/dev/null")
+		out, _ := cmd.CombinedOutput()
+
+		want := "huh [fix] [--command] [false] [--exit-code] [1]"
+		if n := strings.Count(string(out), want); n != 2 {
+			t.Errorf("HISTCONTROL=%s: huh fix got the right command %d times, want 2:\n%s", histcontrol, n, out)
+		}
+	}
+}
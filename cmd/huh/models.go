@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"huh/internal/config"
+	"huh/internal/llm"
+
+	"github.com/spf13/cobra"
+)
+
+var setModel string
+
+var modelsCmd = &cobra.Command{
+	Use:   "models [provider...]",
+	Short: "List the models of the configured providers",
+	Long: "List the models each configured provider offers and check that the configured " +
+		"model is among them. When it isn't, huh offers to pick another one and saves it " +
+		"to the config file. With --set the model is saved directly.",
+	Args: providerArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := config.Override(flagOrEnv(providerName, "HUH_PROVIDER"), flagOrEnv(modelName, "HUH_MODEL")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if setModel != "" {
+			if len(args) > 1 {
+				fmt.Fprintln(os.Stderr, "Error: --set needs a single provider")
+				os.Exit(1)
+			}
+			name := config.AppConfig.DefaultProvider
+			if len(args) == 1 {
+				name = args[0]
+			}
+			if err := saveModel(cmd.Context(), name, setModel); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		names := args
+		if len(names) == 0 {
+			names = providerNames()
+		}
+		var lists []providerModels
+		for _, name := range names {
+			lists = append(lists, listProviderModels(cmd.Context(), name))
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(lists); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			interactive := stdinIsTerminal() && stdoutIsTerminal()
+			in := bufio.NewReader(os.Stdin) // Shared, it may read ahead
+			for i := range lists {
+				printProviderModels(os.Stdout, lists[i], interactive)
+				if interactive && lists[i].missing() && len(lists[i].Models) > 0 {
+					lists[i] = offerModel(in, os.Stdout, lists[i])
+				}
+			}
+		}
+
+		for _, l := range lists {
+			if l.missing() || l.Error != "" {
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	modelsCmd.Flags().StringVar(&setModel, "set", "", "save this model as the provider's model in the config file")
+	rootCmd.AddCommand(askOnBadArgs(modelsCmd))
+}
+
+// providerArgs accepts the names of configured providers. With --set any
+// name is taken, saveModel reports the ones that don't exist.
+func providerArgs(cmd *cobra.Command, args []string) error {
+	if setModel != "" {
+		return nil
+	}
+	for _, name := range args {
+		if _, ok := config.AppConfig.Providers[name]; !ok {
+			return fmt.Errorf("unknown provider %q", name)
+		}
+	}
+	return nil
+}
+
+// providerModels is what one provider offers.
+type providerModels struct {
+	Provider  string   `json:"provider"`
+	Type      string   `json:"type"`
+	Default   bool     `json:"default"`
+	Model     string   `json:"model"`     // Configured model
+	Available bool     `json:"available"` // Whether Model is in Models
+	Models    []string `json:"models"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// missing reports whether the provider listed its models and the
+// configured one isn't among them.
+func (l providerModels) missing() bool {
+	return l.Error == "" && l.Models != nil && !l.Available
+}
+
+func listProviderModels(ctx context.Context, name string) providerModels {
+	l := providerModels{
+		Provider: name,
+		Type:     config.AppConfig.Providers[name].Type,
+		Default:  name == config.AppConfig.DefaultProvider,
+	}
+	p, err := llm.NewProvider(name)
+	if err != nil {
+		l.Error = err.Error()
+		return l
+	}
+	l.Model = p.ModelName()
+	models, err := llm.Models(ctx, p)
+	if errors.Is(err, llm.ErrNoModelList) {
+		return l
+	}
+	if err != nil {
+		l.Error = err.Error()
+		return l
+	}
+	l.Models = models
+	l.Available = llm.HasModel(models, l.Model)
+	return l
+}
+
+// printProviderModels lists l's models, marking the configured one with
+// a star. numbered adds numbers to pick from.
+func printProviderModels(w io.Writer, l providerModels, numbered bool) {
+	title := l.Provider
+	if l.Default {
+		title += " (default)"
+	}
+	fmt.Fprintln(w, title)
+
+	switch {
+	case l.Error != "":
+		fmt.Fprintf(w, "  ✗ %s\n", l.Error)
+	case l.Models == nil:
+		fmt.Fprintf(w, "  %s can't list its models, using %s\n", l.Type, l.Model)
+	case len(l.Models) == 0:
+		fmt.Fprintln(w, "  No models available")
+	}
+	if l.missing() {
+		fmt.Fprintf(w, "  ✗ The configured model %s is not available\n", l.Model)
+		if l.Type == "ollama" {
+			fmt.Fprintf(w, "    Pull it with: ollama pull %s\n", l.Model)
+		}
+	}
+
+	for i, m := range l.Models {
+		mark := " "
+		if llm.HasModel([]string{m}, l.Model) {
+			mark = "*"
+		}
+		if numbered {
+			fmt.Fprintf(w, "  %s %3d. %s\n", mark, i+1, m)
+		} else {
+			fmt.Fprintf(w, "  %s %s\n", mark, m)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// offerModel asks which of l's models to use instead of the missing one
+// and saves the choice. in is shared by every prompt, so nothing it
+// buffered is lost.
+func offerModel(in *bufio.Reader, w io.Writer, l providerModels) providerModels {
+	fmt.Fprintf(w, "Use another model for %s? Enter its number, or nothing to keep %s: ", l.Provider, l.Model)
+	line, _ := in.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return l
+	}
+	n, err := strconv.Atoi(line)
+	if err != nil || n < 1 || n > len(l.Models) {
+		fmt.Fprintf(w, "No model %q, keeping %s.\n", line, l.Model)
+		return l
+	}
+	if err := config.SetProviderParam(l.Provider, "model", l.Models[n-1]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving the config: %v\n", err)
+		return l
+	}
+	fmt.Fprintf(w, "%s now uses %s.\n\n", l.Provider, l.Models[n-1])
+	l.Model, l.Available = l.Models[n-1], true
+	return l
+}
+
+// saveModel sets the model of the named provider in the config file,
+// after checking the provider has it.
+func saveModel(ctx context.Context, name, model string) error {
+	if _, ok := config.AppConfig.Providers[name]; !ok {
+		return fmt.Errorf("provider '%s' not found in configuration", name)
+	}
+	l := listProviderModels(ctx, name)
+	switch {
+	case l.Error != "":
+		fmt.Fprintf(os.Stderr, "Warning: could not check the models of %s: %s\n", name, l.Error)
+	case l.Models != nil && !llm.HasModel(l.Models, model):
+		return fmt.Errorf("%s has no model %s, see huh models %s", name, model, name)
+	}
+	if err := config.SetProviderParam(name, "model", model); err != nil {
+		return err
+	}
+	fmt.Printf("%s now uses %s.\n", name, model)
+	return nil
+}
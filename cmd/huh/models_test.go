@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestOfferModelSharesInput(t *testing.T) {
+	// Piped answers arrive together, the second prompt must still get its own
+	in := bufio.NewReader(strings.NewReader("7\n8\n"))
+	var out bytes.Buffer
+	lists := []providerModels{
+		{Provider: "ollama", Model: "llama3:8b", Models: []string{"qwen2.5:7b"}},
+		{Provider: "gpu-box", Model: "mistral", Models: []string{"qwen2.5:7b"}},
+	}
+	for i := range lists {
+		lists[i] = offerModel(in, &out, lists[i])
+	}
+
+	for _, want := range []string{`No model "7", keeping llama3:8b.`, `No model "8", keeping mistral.`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output doesn't say %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"huh/internal/ui"
+)
+
+// printResult is the --json output of the non-interactive mode.
+type printResult struct {
+	Question   string   `json:"question"`
+	Answer     string   `json:"answer"`
+	Commands   []string `json:"commands"`
+	Provider   string   `json:"provider"`
+	Model      string   `json:"model"`
+	Cached     bool     `json:"cached"`
+	DurationMs int64    `json:"duration_ms"`
+	Redacted   []string `json:"redacted,omitempty"` // Secrets masked before sending
+	Error      string   `json:"error,omitempty"`
+}
+
+// runPrint answers question without the TUI. Only the extracted commands
+// are written to w (or the whole answer if it has none), so the output can
+// be piped or captured. The error is set if the provider failed.
+func runPrint(w io.Writer, sess *session, question, attachedContent string, asJSON bool) (printResult, error) {
+	start := time.Now()
+	answer, err := sess.Query(sess.ctx, question, attachedContent, nil)
+	res := printResult{
+		Question:   question,
+		Answer:     answer,
+		Commands:   ui.ParseCommands(answer),
+		Provider:   sess.ProviderName(),
+		Model:      sess.ModelName(),
+		Cached:     sess.FromCache(),
+		Redacted:   sess.Redacted(),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if res.Commands == nil {
+		res.Commands = []string{}
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(res); encErr != nil {
+			return res, encErr
+		}
+		return res, err
+	}
+	if err != nil {
+		return res, err
+	}
+
+	if len(res.Commands) == 0 {
+		_, werr := fmt.Fprintln(w, strings.TrimSpace(answer))
+		return res, werr
+	}
+	for _, c := range res.Commands {
+		if _, werr := fmt.Fprintln(w, c); werr != nil {
+			return res, werr
+		}
+	}
+	return res, nil
+}
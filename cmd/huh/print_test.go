@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"huh/internal/llm"
+	"huh/internal/usercontext"
+)
+
+func TestAskPrint(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Cleanup(func() { jsonOutput = false })
+
+	unreachable := &llm.RequestError{Provider: "fake", Kind: llm.ErrNetwork, Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name       string
+		question   string
+		asJSON     bool
+		provider   *fakeLLM
+		wantCode   int
+		wantStdout string // Exact, for the text output
+		wantStderr string
+	}{
+		{
+			name:       "commands only",
+			question:   "list files",
+			provider:   &fakeLLM{answer: "Use:\n```bash\nls -la\n```\nor\n```bash\nls -A\n```"},
+			wantStdout: "ls -la\nls -A\n",
+		},
+		{
+			name:       "answer without commands",
+			question:   "what is a pid?",
+			provider:   &fakeLLM{answer: "A process ID.\n"},
+			wantStdout: "A process ID.\n",
+		},
+		{
+			name:       "no question",
+			question:   " ",
+			provider:   &fakeLLM{},
+			wantCode:   1,
+			wantStderr: "a question is required",
+		},
+		{
+			name:       "provider error",
+			question:   "list files",
+			provider:   &fakeLLM{err: unreachable},
+			wantCode:   1,
+			wantStderr: "connection refused",
+		},
+		{
+			name:       "provider error as JSON",
+			question:   "list files",
+			asJSON:     true,
+			provider:   &fakeLLM{err: unreachable},
+			wantCode:   1,
+			wantStderr: "connection refused",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonOutput = tt.asJSON
+			sess := newSession(context.Background(), tt.provider, usercontext.SystemContext{OS: "linux", Shell: "bash"})
+			var stdout, stderr bytes.Buffer
+			code := askPrint(&stdout, &stderr, sess, tt.question, "", "")
+			if code != tt.wantCode {
+				t.Errorf("exit status = %d, want %d", code, tt.wantCode)
+			}
+			if !tt.asJSON && stdout.String() != tt.wantStdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.wantStdout)
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr.String(), tt.wantStderr)
+			}
+			if tt.asJSON {
+				var res map[string]any
+				if err := json.Unmarshal(stdout.Bytes(), &res); err != nil {
+					t.Fatalf("stdout isn't JSON: %v: %q", err, stdout.String())
+				}
+				if res["error"] != unreachable.Error() {
+					t.Errorf("error = %v, want the provider's error", res["error"])
+				}
+			}
+		})
+	}
+}
+
+func TestRunPrintJSON(t *testing.T) {
+	sess := newSession(context.Background(), &fakeLLM{answer: "Use:\n```bash\ndf -h\n```"}, usercontext.SystemContext{OS: "linux", Shell: "bash"})
+	var out bytes.Buffer
+	if _, err := runPrint(&out, sess, "disk space?", "", true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Scripts rely on these fields and their types
+	var res map[string]any
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatalf("not JSON: %v: %q", err, out.String())
+	}
+	var keys []string
+	for k := range res {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	want := []string{"answer", "cached", "commands", "duration_ms", "model", "provider", "question"}
+	if !slices.Equal(keys, want) {
+		t.Errorf("keys = %q, want %q", keys, want)
+	}
+	if res["question"] != "disk space?" || res["provider"] != "fake" || res["model"] != "fake-1" || res["cached"] != false {
+		t.Errorf("result = %v", res)
+	}
+	if cmds, ok := res["commands"].([]any); !ok || len(cmds) != 1 || cmds[0] != "df -h" {
+		t.Errorf("commands = %#v, want [df -h]", res["commands"])
+	}
+	if _, ok := res["duration_ms"].(float64); !ok {
+		t.Errorf("duration_ms = %#v, want a number", res["duration_ms"])
+	}
+
+	// Commands is an empty list rather than null when there are none
+	sess = newSession(context.Background(), &fakeLLM{answer: "No command needed."}, usercontext.SystemContext{})
+	out.Reset()
+	if _, err := runPrint(&out, sess, "hi", "", true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"commands": []`) {
+		t.Errorf("output = %s, want an empty commands list", out.String())
+	}
+}
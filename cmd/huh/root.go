@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 
+	"huh/internal/attach"
 	"huh/internal/config"
+	"huh/internal/history"
 	"huh/internal/llm"
 	"huh/internal/ui"
 	"huh/internal/usercontext"
@@ -17,17 +20,41 @@ import (
 
 var files []string
 var showConfigLocation bool
+var printMode bool
+var jsonOutput bool
+var outputPath string
+var noCache bool
+var providerName string
+var modelName string
 
 func init() {
-	rootCmd.Flags().StringSliceVarP(&files, "file", "f", []string{}, "file(s) to attach")
+	// Shared with the subcommands that ask a question, like fix
+	rootCmd.PersistentFlags().StringSliceVarP(&files, "file", "f", []string{}, "file(s) to attach")
+	rootCmd.PersistentFlags().BoolVarP(&printMode, "print", "p", false, "print the command(s) to stdout instead of starting the TUI")
+	rootCmd.PersistentFlags().BoolVar(&printMode, "no-tui", false, "same as --print")
+	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "print the answer, commands, provider, model and timing as JSON (implies --print)")
+	rootCmd.PersistentFlags().StringVarP(&outputPath, "output", "o", "", "write the chosen command to this file instead of the clipboard")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "don't use the response cache")
+	rootCmd.PersistentFlags().StringVar(&providerName, "provider", "", "use this configured provider instead of the default (or set HUH_PROVIDER)")
+	rootCmd.PersistentFlags().StringVar(&modelName, "model", "", "use this model instead of the provider's configured one (or set HUH_MODEL)")
+
+	rootCmd.PersistentPreRun = requireConfig
+
 	rootCmd.Flags().BoolVarP(&showConfigLocation, "config-location", "c", false, "show the location of the config file")
+
+	// Questions often start with "help", so don't let cobra's help and
+	// completion commands swallow them. --help still works.
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.SetHelpCommand(&cobra.Command{Hidden: true})
 }
 
 var rootCmd = &cobra.Command{
 	Use:   "huh [question]",
 	Short: "huh is your terminal AI assistant",
-	Long:  `huh translates natural language questions into terminal commands.`,
-	Args:  cobra.MaximumNArgs(100), // Allow any number, we join them. If 0, we enter interactive.
+	Long: "huh translates natural language questions into terminal commands.\n\n" +
+		"A question that starts with fix or history is taken by those subcommands, " +
+		"put -- in front of it to ask it: huh -- fix my wifi",
+	Args: cobra.MaximumNArgs(100), // Allow any number, we join them. If 0, we enter interactive.
 	Run: func(cmd *cobra.Command, args []string) {
 		if showConfigLocation {
 			path, err := config.GetConfigLocation()
@@ -40,125 +67,259 @@ var rootCmd = &cobra.Command{
 		}
 
 		question := strings.Join(args, " ")
+		sess := startSession(cmd)
+		attachedContent, contextInfo := readAttachments("Stdin", sess.AttachmentBudget())
+		ask(sess, question, contextInfo, attachedContent)
+	},
+}
 
-		// 1. Gather Context
-		sysCtx := usercontext.GetContext()
+// attachment frames content the way every attachment is sent to the model.
+func attachment(title, content string) string {
+	return fmt.Sprintf("\n--- %s ---\n%s\n", title, content)
+}
 
-		// 2. Read Attachments
-		var contextBuilder strings.Builder
-		var contextInfoParts []string
+// readAttachments reads the --file arguments and piped stdin, sharing
+// budget bytes between them. It returns the framed content and a short
+// description for the TUI header that says what had to be trimmed.
+func readAttachments(stdinTitle string, budget int) (string, string) {
+	var contextBuilder strings.Builder
+	var contextInfoParts []string
 
-		for _, f := range files {
-			b, err := os.ReadFile(f)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: could not read %s: %v\n", f, err)
-				continue
-			}
-			contextBuilder.WriteString(fmt.Sprintf("\n--- File: %s ---\n%s\n", f, string(b)))
-			contextInfoParts = append(contextInfoParts, f)
+	piped := !stdinIsTerminal()
+	count := len(files)
+	if piped {
+		count++
+	}
+	if count == 0 {
+		return "", ""
+	}
+	share := budget / count
+
+	for _, f := range files {
+		content, stats, err := attach.ReadFile(f, share)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not read %s: %v\n", f, err)
+			continue
 		}
+		contextBuilder.WriteString(attachment("File: "+f, content))
+		contextInfoParts = append(contextInfoParts, attach.Describe(f, stats))
+	}
 
-		stat, _ := os.Stdin.Stat()
-		if (stat.Mode() & os.ModeCharDevice) == 0 {
-			b, err := io.ReadAll(os.Stdin)
-			if err == nil {
-				contextBuilder.WriteString(fmt.Sprintf("\n--- Stdin ---\n%s\n", string(b)))
-				contextInfoParts = append(contextInfoParts, "Stdin")
-			}
+	if piped {
+		content, stats, err := attach.Reduce(os.Stdin, share)
+		if err == nil && stats.Lines > 0 {
+			contextBuilder.WriteString(attachment(stdinTitle, content))
+			contextInfoParts = append(contextInfoParts, attach.Describe(stdinTitle, stats))
 		}
+	}
 
-		attachedContent := contextBuilder.String()
-		contextInfo := strings.Join(contextInfoParts, ", ")
+	return contextBuilder.String(), strings.Join(contextInfoParts, ", ")
+}
 
-		// 3. Setup Provider
-		provider, err := llm.NewProvider("")
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error creating provider: %v\n", err)
-			os.Exit(1)
+// ask answers question, either in the TUI or, for scripts, on stdout.
+func ask(sess *session, question, contextInfo, attachedContent string) {
+	// Print mode, for scripts, pipelines and editors.
+	// The shell integration captures the command through --output, so
+	// stdout may be anything there and the TUI is still wanted.
+	if printMode || jsonOutput || (outputPath == "" && !stdoutIsTerminal()) {
+		if code := askPrint(os.Stdout, os.Stderr, sess, question, contextInfo, attachedContent); code != 0 {
+			os.Exit(code)
 		}
+		return
+	}
 
-		// 4. Define Query Function
-		queryFunc := func(q string, dynamicContext string) (string, error) {
-			finalQuestion := q
-			
-			// Context is managed by the UI model and passed as dynamicContext
-			if dynamicContext != "" {
-				finalQuestion = fmt.Sprintf("%s\n\nAttached Context:\n%s", q, dynamicContext)
-			}
+	runTUI(newModel(sess, question, contextInfo, attachedContent))
+}
 
-            // Build User Context String
-            var customContext strings.Builder
-            if len(sysCtx.Custom) > 0 {
-                customContext.WriteString("User Info: ")
-                for k, v := range sysCtx.Custom {
-                    customContext.WriteString(fmt.Sprintf("%s=%s; ", k, v))
-                }
-            }
-
-			baseSystemPrompt := config.AppConfig.SystemPrompt
-			if baseSystemPrompt == "" {
-				baseSystemPrompt = "If the user asks for a command, provide it inside a markdown code block, like:\n" +
-					"```bash\ncommand here\n```\n" +
-					"You can also provide a brief explanation outside the block. If the user asks a question, answer it normally."
-			}
+// askPrint is ask without the TUI: the answer goes to stdout, everything
+// else to stderr. It returns the exit status.
+func askPrint(stdout, stderr io.Writer, sess *session, question, contextInfo, attachedContent string) int {
+	if strings.TrimSpace(question) == "" {
+		fmt.Fprintln(stderr, "Error: a question is required when not running interactively")
+		return 1
+	}
+	if redacted := sess.PreviewRedaction(question + "\n" + attachedContent); len(redacted) > 0 && !jsonOutput {
+		fmt.Fprintf(stderr, "Redacting before sending: %s\n", strings.Join(redacted, ", "))
+	}
+	res, err := runPrint(stdout, sess, question, attachedContent, jsonOutput)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	recordHistory(history.Entry{
+		Question:    question,
+		Attachments: splitInfo(contextInfo),
+		Provider:    res.Provider,
+		Model:       res.Model,
+		Answer:      res.Answer,
+		Commands:    res.Commands,
+		Action:      "print",
+	})
+	return 0
+}
 
-			systemPrompt := fmt.Sprintf(
-				"Context: OS: %s, Distro: %s, Shell: %s. %s\n"+
-				"User Query: '%s'.\n"+
-				"%s",
-				sysCtx.OS, sysCtx.Distro, sysCtx.Shell, customContext.String(), q, baseSystemPrompt,
-			)
-			
-			return provider.Query(cmd.Context(), systemPrompt, finalQuestion)
-		}
+// startSession sets up the configured provider, exiting if it can't.
+func startSession(cmd *cobra.Command) *session {
+	provider, err := connect()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return newSession(cmd.Context(), provider, usercontext.GetContext())
+}
 
-		// 5. Define Explain Function
-		explainFunc := func(command string, dynamicContext string) (string, error) {
-			prompt := fmt.Sprintf("Explain the following command briefly: '%s'", command)
-			
-			if dynamicContext != "" {
-				prompt += fmt.Sprintf("\n\nContext:\n%s", dynamicContext)
-			}
-			return provider.Query(cmd.Context(), "You are a helpful assistant explaining Linux commands. Be concise.", prompt)
-		}
+// startLazySession is startSession for when a request may never be made,
+// like browsing history. The provider is set up on the first request, and
+// failing to is reported in the TUI.
+func startLazySession(cmd *cobra.Command) *session {
+	sess := newSession(cmd.Context(), nil, usercontext.GetContext())
+	sess.connect = connect
+	return sess
+}
 
-		// 6. Define Refine Function
-		refineFunc := func(originalCommand, refinement, dynamicContext string) (string, error) {
-			refinePrompt := fmt.Sprintf(
-				"Original Request: '%s'. Original Command: '%s'. Refinement Request: '%s'.\n"+
-					"Return the updated command inside a markdown code block:\n"+
-					"```bash\nnew command\n```\n"+
-					"You may explain the change briefly if needed.",
-				question, originalCommand, refinement,
-			)
-			
-			if dynamicContext != "" {
-				refinePrompt += fmt.Sprintf("\n\nContext:\n%s", dynamicContext)
-			}
-			systemPrompt := fmt.Sprintf("You are a command line helper for %s. Update the command based on user request.", sysCtx.Distro)
-			return provider.Query(cmd.Context(), systemPrompt, refinePrompt)
+// connect sets up the configured provider, with --provider, --model and
+// --no-cache applied.
+func connect() (llm.LLM, error) {
+	if noCache {
+		config.AppConfig.Cache.Enabled = false
+	}
+	if err := config.Override(flagOrEnv(providerName, "HUH_PROVIDER"), flagOrEnv(modelName, "HUH_MODEL")); err != nil {
+		return nil, err
+	}
+	provider, err := llm.NewProvider("")
+	if err != nil {
+		return nil, fmt.Errorf("creating provider: %w", err)
+	}
+	return provider, nil
+}
+
+// flagOrEnv returns the flag's value, or the environment variable's if
+// the flag wasn't given.
+func flagOrEnv(flag, env string) string {
+	if flag != "" {
+		return flag
+	}
+	return os.Getenv(env)
+}
+
+// newModel builds the TUI model on top of sess.
+func newModel(sess *session, question, contextInfo, attachedContent string) ui.Model {
+	model := ui.NewModel(question, contextInfo, attachedContent, sess.Query, sess.Explain, sess.Refine)
+	model.ProviderFunc = sess.ProviderName
+	model.ModelFunc = sess.ModelName
+	model.CachedFunc = sess.FromCache
+	model.RedactedFunc = sess.Redacted
+	model.PreviewRedactionFunc = sess.PreviewRedaction
+	model.Regenerate = sess.Regenerate
+	model.Rewind = sess.Rewind
+	model.Shell = sess.sysCtx.Shell
+	model.Providers = providerNames()
+	model.SwitchProvider = sess.SwitchProvider
+	model.OutputPath = outputPath
+	model.AttachmentBudget = sess.AttachmentBudget()
+	model.LoadHistory = loadHistory
+	model.ReviewRedaction()
+	return model
+}
+
+// runTUI runs model until the user quits and records what they did.
+func runTUI(model ui.Model) {
+	opts := []tea.ProgramOption{tea.WithOutput(os.Stderr)}
+	if !stdinIsTerminal() {
+		f, err := os.Open("/dev/tty")
+		if err == nil {
+			defer f.Close()
+			opts = append(opts, tea.WithInput(f))
 		}
+	}
 
-		// 7. Start TUI
-		opts := []tea.ProgramOption{tea.WithOutput(os.Stderr)}
-		if (stat.Mode() & os.ModeCharDevice) == 0 {
-			f, err := os.Open("/dev/tty")
-			if err == nil {
-				defer f.Close()
-				opts = append(opts, tea.WithInput(f))
-			}
+	p := tea.NewProgram(model, opts...)
+	final, err := p.Run()
+	if err != nil {
+		fmt.Printf("Error running TUI: %v\n", err)
+		os.Exit(1)
+	}
+	if m, ok := final.(ui.Model); ok {
+		recordModel(m)
+	}
+}
+
+// stdoutIsTerminal reports whether stdout is a terminal. When it isn't,
+// huh is being piped or captured and the TUI would only get in the way.
+func stdoutIsTerminal() bool {
+	stat, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+func stdinIsTerminal() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return true
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// providerNames returns the configured providers, default first.
+func providerNames() []string {
+	names := []string{config.AppConfig.DefaultProvider}
+	var others []string
+	for name := range config.AppConfig.Providers {
+		if name != config.AppConfig.DefaultProvider {
+			others = append(others, name)
 		}
+	}
+	sort.Strings(others)
+	return append(names, others...)
+}
+
+// configErr is why the config file couldn't be loaded or is invalid, if
+// it is.
+var configErr error
 
-		p := tea.NewProgram(ui.NewModel(question, contextInfo, attachedContent, queryFunc, explainFunc, refineFunc), opts...)
-		if _, err := p.Run(); err != nil {
-			fmt.Printf("Error running TUI: %v\n", err)
-			os.Exit(1)
+// requireConfig stops before anything is asked when the config is broken,
+// rather than failing later with a less helpful error. Doctor reports the
+// problem instead, and the shell integration and -c don't need a config.
+func requireConfig(cmd *cobra.Command, args []string) {
+	if configErr == nil || showConfigLocation || cmd == doctorCmd || cmd == initCmd {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\nFix it and check again with huh doctor.\n", configErr)
+	os.Exit(1)
+}
+
+// askOnBadArgs makes cmd ask its arguments as a question, its own name
+// first, when they aren't ones it takes. Questions often start with a
+// subcommand's name, like "huh init a git repo" or "huh doctor my
+// resume". Without arguments cmd runs, or fails, as usual.
+func askOnBadArgs(cmd *cobra.Command) *cobra.Command {
+	valid := cmd.Args
+	run, runE := cmd.Run, cmd.RunE
+	cmd.Args = cobra.ArbitraryArgs
+	cmd.Run = nil
+	cmd.RunE = func(c *cobra.Command, args []string) error {
+		err := valid(c, args)
+		if err != nil && len(args) > 0 {
+			requireConfig(rootCmd, args)
+			rootCmd.Run(c, append([]string{c.Name()}, args...))
+			return nil
 		}
-	},
+		if err != nil {
+			return err
+		}
+		if runE != nil {
+			return runE(c, args)
+		}
+		run(c, args)
+		return nil
+	}
+	return cmd
 }
 
 func Execute() {
-	config.Init()
+	configErr = config.Init()
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"huh/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+func TestQuestionsStartingWithSubcommands(t *testing.T) {
+	savedRun, savedConfig := rootCmd.Run, config.AppConfig
+	t.Cleanup(func() {
+		rootCmd.Run, config.AppConfig = savedRun, savedConfig
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+	})
+	config.AppConfig = config.Config{
+		DefaultProvider: "ollama",
+		Providers:       map[string]config.ProviderConfig{"ollama": {Type: "ollama"}},
+	}
+
+	tests := []struct {
+		args    []string
+		asked   string // Empty if the subcommand should run
+		wantOut string
+		wantErr bool
+	}{
+		{[]string{"init", "a", "git", "repo"}, "init a git repo", "", false},
+		{[]string{"init", "bash"}, "", "bind", false},
+		{[]string{"init"}, "", "", true},
+		{[]string{"doctor", "my", "resume"}, "doctor my resume", "", false},
+		{[]string{"models", "for", "images"}, "models for images", "", false},
+		{[]string{"--", "fix", "my", "wifi"}, "fix my wifi", "", false},
+		{[]string{"-p", "--", "history", "of", "ls"}, "history of ls", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			t.Cleanup(func() { printMode = false })
+			var asked string
+			rootCmd.Run = func(cmd *cobra.Command, args []string) {
+				asked = strings.Join(args, " ")
+			}
+			var out bytes.Buffer
+			rootCmd.SetOut(&out)
+			rootCmd.SetErr(io.Discard)
+			rootCmd.SetArgs(tt.args)
+
+			err := rootCmd.Execute()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if asked != tt.asked {
+				t.Errorf("asked %q, want %q", asked, tt.asked)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output = %q, want it to contain %q", out.String(), tt.wantOut)
+			}
+		})
+	}
+}
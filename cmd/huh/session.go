@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"huh/internal/attach"
+	"huh/internal/config"
+	"huh/internal/llm"
+	"huh/internal/usercontext"
+)
+
+// session holds one exchange with the provider. The conversation is shared
+// by Query, Explain and Refine so that follow-ups carry everything said so
+// far. sentContext tracks how much of the attached context the model has
+// already seen.
+type session struct {
+	ctx         context.Context // For requests made outside the TUI, which has its own
+	provider    llm.LLM
+	connect     func() (llm.LLM, error) // Sets up provider on the first request, if it's nil
+	sysCtx      usercontext.SystemContext
+	conv        *llm.Conversation
+	sentContext string
+
+	// State before the last Query or Refine, so Regenerate can send it again
+	prevMessages    []llm.Message
+	prevSentContext string
+	refresh         bool // Skip the response cache for the next request
+}
+
+func newSession(ctx context.Context, provider llm.LLM, sysCtx usercontext.SystemContext) *session {
+	return &session{
+		ctx:      ctx,
+		provider: provider,
+		sysCtx:   sysCtx,
+		conv:     &llm.Conversation{},
+	}
+}
+
+// ready sets up the provider if the session started without one.
+func (s *session) ready() error {
+	if s.provider != nil {
+		return nil
+	}
+	p, err := s.connect()
+	if err != nil {
+		return err
+	}
+	s.provider = p
+	return nil
+}
+
+// send sends prompt as the next user message and records how much of the
+// attached context has been sent.
+func (s *session) send(ctx context.Context, prompt, dynamicContext string, onChunk func(string)) (string, error) {
+	if s.refresh {
+		ctx = llm.RefreshCache(ctx)
+		s.refresh = false
+	}
+	res, err := s.conv.Send(ctx, s.provider, prompt, onChunk)
+	if err == nil {
+		s.sentContext = dynamicContext
+	}
+	return res, err
+}
+
+// checkpoint remembers the conversation before a new suggestion is asked for.
+func (s *session) checkpoint() {
+	s.prevMessages = append([]llm.Message(nil), s.conv.Messages...)
+	s.prevSentContext = s.sentContext
+}
+
+func (s *session) unsentContext(dynamicContext string) string {
+	if s.sentContext != "" && strings.HasPrefix(dynamicContext, s.sentContext) {
+		return dynamicContext[len(s.sentContext):]
+	}
+	return dynamicContext
+}
+
+// Query starts a new conversation with the system prompt built from the
+// user's context.
+func (s *session) Query(ctx context.Context, q string, dynamicContext string, onChunk func(string)) (string, error) {
+	if err := s.ready(); err != nil {
+		return "", err
+	}
+	finalQuestion := q
+
+	// Context is managed by the UI model and passed as dynamicContext
+	if dynamicContext != "" {
+		finalQuestion = fmt.Sprintf("%s\n\nAttached Context:\n%s", q, s.fit(dynamicContext))
+	}
+
+	// Build User Context String
+	var customContext strings.Builder
+	if len(s.sysCtx.Custom) > 0 {
+		customContext.WriteString("User Info: ")
+		for k, v := range s.sysCtx.Custom {
+			customContext.WriteString(fmt.Sprintf("%s=%s; ", k, v))
+		}
+	}
+
+	if s.sysCtx.Hardware != nil {
+		customContext.WriteString(fmt.Sprintf("Hardware: %s. ", s.sysCtx.Hardware))
+	}
+	if s.sysCtx.Git != nil {
+		customContext.WriteString(fmt.Sprintf("Current git repository: %s. ", s.sysCtx.Git))
+	}
+	if s.sysCtx.Project != nil {
+		customContext.WriteString(fmt.Sprintf("Project in the working directory: %s. ", s.sysCtx.Project))
+	}
+
+	baseSystemPrompt := config.AppConfig.SystemPrompt
+	if baseSystemPrompt == "" {
+		baseSystemPrompt = "If the user asks for a command, provide it inside a markdown code block, like:\n" +
+			"```bash\ncommand here\n```\n" +
+			"You can also provide a brief explanation outside the block. If the user asks a question, answer it normally."
+	}
+
+	systemPrompt := fmt.Sprintf(
+		"Context: %s. %s\n"+
+			"User Query: '%s'.\n"+
+			"%s",
+		s.sysCtx.Describe(), customContext.String(), q, baseSystemPrompt,
+	)
+
+	s.checkpoint()
+	s.conv.Reset(systemPrompt)
+	s.sentContext = ""
+	return s.send(ctx, finalQuestion, dynamicContext, onChunk)
+}
+
+// Explain asks for an explanation of command as a follow-up.
+func (s *session) Explain(ctx context.Context, command string, dynamicContext string) (string, error) {
+	if err := s.ready(); err != nil {
+		return "", err
+	}
+	prompt := fmt.Sprintf("Explain the following command briefly: '%s'", command)
+
+	if newContext := s.unsentContext(dynamicContext); newContext != "" {
+		prompt += fmt.Sprintf("\n\nContext:\n%s", s.fit(newContext))
+	}
+	if len(s.conv.Messages) == 0 {
+		s.conv.Reset("You are a helpful assistant explaining Linux commands. Be concise.")
+	}
+	return s.send(ctx, prompt, dynamicContext, nil)
+}
+
+// Refine asks for an updated version of originalCommand as a follow-up.
+func (s *session) Refine(ctx context.Context, originalCommand, refinement, dynamicContext string, onChunk func(string)) (string, error) {
+	if err := s.ready(); err != nil {
+		return "", err
+	}
+	refinePrompt := fmt.Sprintf(
+		"Update this command: '%s'. Refinement Request: '%s'.\n"+
+			"Return the updated command inside a markdown code block:\n"+
+			"```bash\nnew command\n```\n"+
+			"You may explain the change briefly if needed.",
+		originalCommand, refinement,
+	)
+
+	if newContext := s.unsentContext(dynamicContext); newContext != "" {
+		refinePrompt += fmt.Sprintf("\n\nContext:\n%s", s.fit(newContext))
+	}
+	s.checkpoint()
+	if len(s.conv.Messages) == 0 {
+		s.conv.Reset(fmt.Sprintf("You are a command line helper for %s. Update the command based on user request.", s.sysCtx.Distro))
+	}
+	return s.send(ctx, refinePrompt, dynamicContext, onChunk)
+}
+
+// ProviderName is the name of the provider that answered last.
+func (s *session) ProviderName() string {
+	if s.provider == nil {
+		return ""
+	}
+	return s.provider.Name()
+}
+
+// ModelName is the model of the provider that answered last.
+func (s *session) ModelName() string {
+	if s.provider == nil {
+		return ""
+	}
+	return s.provider.ModelName()
+}
+
+// FromCache reports whether the last answer came from the response cache.
+func (s *session) FromCache() bool {
+	return llm.FromCache(s.provider)
+}
+
+// AttachmentBudget is how many bytes of attachments fit in the context
+// window of the provider.
+func (s *session) AttachmentBudget() int {
+	return llm.AttachmentBudget(s.provider) * llm.BytesPerToken
+}
+
+// fit reduces attachments that grew past the budget in the TUI, by adding
+// files or command output, so they still fit.
+func (s *session) fit(content string) string {
+	budget := llm.AttachmentBudget(s.provider)
+	tokens := llm.EstimateTokens(content)
+	if tokens <= budget {
+		return content
+	}
+	// Non-ASCII text takes fewer bytes per token, so the byte budget is
+	// scaled by what this content takes
+	reduced, _ := attach.ReduceString(content, len(content)*budget/tokens)
+	return reduced
+}
+
+// Redacted lists the secrets that were masked in the last request, like
+// "JWT eyJh…R8U".
+func (s *session) Redacted() []string {
+	var redacted []string
+	for _, f := range llm.Redacted(s.provider) {
+		redacted = append(redacted, f.String())
+	}
+	return redacted
+}
+
+// PreviewRedaction lists the secrets that would be masked in content
+// before it is sent.
+func (s *session) PreviewRedaction(content string) []string {
+	var redacted []string
+	for _, f := range llm.PreviewRedaction(s.provider, content) {
+		redacted = append(redacted, f.String())
+	}
+	return redacted
+}
+
+// Rewind rolls the conversation back to before the last suggestion, so it
+// can be asked for again.
+func (s *session) Rewind() {
+	s.conv.Messages = s.prevMessages
+	s.sentContext = s.prevSentContext
+}
+
+// Regenerate rewinds and makes the next request bypass the response cache.
+func (s *session) Regenerate() {
+	s.Rewind()
+	s.refresh = true
+}
+
+// SwitchProvider sends the rest of the conversation to the named provider.
+func (s *session) SwitchProvider(name string) error {
+	p, err := llm.NewProvider(name)
+	if err != nil {
+		return err
+	}
+	s.provider = p
+	return nil
+}
@@ -0,0 +1,43 @@
+# huh shell integration for bash. Add to ~/.bashrc:
+#   eval "$(huh init bash)"
+# Ctrl-G asks huh about the current line and replaces it with the command
+# you pick.
+
+__huh_widget() {
+    local tmp
+    tmp=$(mktemp "${TMPDIR:-/tmp}/huh.XXXXXX") || return
+    huh --output "$tmp" -- "$READLINE_LINE" </dev/tty
+    if [[ -s $tmp ]]; then
+        READLINE_LINE=$(<"$tmp")
+        READLINE_POINT=${#READLINE_LINE}
+    fi
+    rm -f -- "$tmp"
+}
+
+bind -x '"\C-g": __huh_widget'
+
+# "huh fix" picks up the previous command and its exit code from here.
+# The huh fix line itself may or may not be in the history, depending on
+# HISTCONTROL, so it's skipped if it's there.
+__huh_last_cmd() {
+    local last
+    last=$(builtin fc -ln -1 2>/dev/null)
+    last=${last#"${last%%[![:space:]]*}"}
+    if [[ $last == "huh fix" || $last == "huh fix "* ]]; then
+        last=$(builtin fc -ln -2 -2 2>/dev/null)
+        last=${last#"${last%%[![:space:]]*}"}
+    fi
+    printf '%s' "$last"
+}
+
+huh() {
+    local code=$?
+    if [[ $1 == fix ]]; then
+        shift
+        local last
+        last=$(__huh_last_cmd)
+        command huh fix --command "$last" --exit-code "$code" "$@"
+    else
+        command huh "$@"
+    fi
+}
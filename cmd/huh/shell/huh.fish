@@ -0,0 +1,34 @@
+# huh shell integration for fish. Add to ~/.config/fish/config.fish:
+#   huh init fish | source
+# Ctrl-G asks huh about the current line and replaces it with the command
+# you pick.
+
+function __huh_widget
+    set -l tmp (mktemp); or return
+    huh --output $tmp -- (commandline) </dev/tty
+    if test -s $tmp
+        commandline -r -- (cat $tmp | string collect)
+        commandline -f end-of-line
+    end
+    rm -f -- $tmp
+    commandline -f repaint
+end
+
+bind \cg __huh_widget
+if bind -M insert >/dev/null 2>&1
+    bind -M insert \cg __huh_widget
+end
+
+# "huh fix" picks up the previous command and its exit code from here.
+function __huh_postexec --on-event fish_postexec
+    set -g __huh_last_status $status
+    set -g __huh_last_cmd $argv[1]
+end
+
+function huh
+    if test "$argv[1]" = fix; and test -n "$__huh_last_cmd"
+        command huh fix --command $__huh_last_cmd --exit-code $__huh_last_status $argv[2..-1]
+    else
+        command huh $argv
+    end
+end
@@ -0,0 +1,263 @@
+// Package attach reads attachments and shrinks the ones that don't fit in
+// the model's context window, keeping the parts that matter in a log: the
+// start, the end and anything that looks like an error.
+package attach
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// maxLineBytes is where long lines, like minified JSON, are cut when an
+// attachment has to be reduced.
+const maxLineBytes = 2048
+
+// errorRe matches the lines worth keeping from the middle of a log.
+var errorRe = regexp.MustCompile(`(?i)\b(error|err|fatal|panic|exception|traceback|fail(ed|ure)?|denied|refused|segfault|critical|warn(ing)?|timed? ?out)\b`)
+
+// errorWords rule out most lines before errorRe has to look at them.
+var errorWords = []string{"err", "fatal", "panic", "exception", "traceback", "fail", "denied", "refused", "segfault", "critical", "warn", "time"}
+
+func isError(text string) bool {
+	lower := strings.ToLower(text)
+	for _, w := range errorWords {
+		if strings.Contains(lower, w) {
+			return errorRe.MatchString(text)
+		}
+	}
+	return false
+}
+
+// Stats describes what Reduce did.
+type Stats struct {
+	Lines    int // Lines read
+	Kept     int // Lines kept
+	Repeated int // Lines replaced by a note that the line before repeats
+}
+
+// Trimmed reports whether anything was left out.
+func (s Stats) Trimmed() bool {
+	return s.Kept < s.Lines
+}
+
+// String describes the trimming for the TUI header, e.g.
+// "kept 412 of 2,000,000 lines".
+func (s Stats) String() string {
+	if !s.Trimmed() {
+		return ""
+	}
+	if s.Kept+s.Repeated == s.Lines {
+		return fmt.Sprintf("%s repeated lines collapsed", thousands(s.Repeated))
+	}
+	return fmt.Sprintf("kept %s of %s lines", thousands(s.Kept), thousands(s.Lines))
+}
+
+// line is a kept line and how often it repeated right after itself.
+type line struct {
+	n       int // Line number, from 1
+	text    string
+	repeats int
+}
+
+// collapsed reports whether l is written once with a note about its
+// repeats. A single repeat is cheaper to just write out. With expand set
+// nothing is collapsed.
+func (l line) collapsed(expand bool) bool {
+	return !expand && l.repeats > 1
+}
+
+func (l line) render(expand bool) string {
+	if l.collapsed(expand) {
+		return fmt.Sprintf("%s\n[... previous line repeated %d times ...]", l.text, l.repeats)
+	}
+	return strings.TrimSuffix(strings.Repeat(l.text+"\n", l.repeats+1), "\n")
+}
+
+func (l line) size(expand bool) int {
+	if l.collapsed(expand) {
+		return len(l.text) + 45
+	}
+	return (len(l.text) + 1) * (l.repeats + 1)
+}
+
+// Reduce reads r line by line and returns at most about maxBytes of it.
+// Content that fits is returned as is. Otherwise long lines are cut, runs
+// of identical lines collapsed, and a quarter of the budget goes to the
+// head, a quarter to the error lines in between and the rest to the tail,
+// where the interesting part of a log usually is. Only what is kept is
+// held in memory, so r can be arbitrarily large.
+func Reduce(r io.Reader, maxBytes int) (string, Stats, error) {
+	headBudget := maxBytes / 4
+	errBudget := maxBytes / 4
+	tailBudget := maxBytes - headBudget - errBudget
+
+	var (
+		stats             Stats
+		all               []line // Everything, until it stops fitting
+		allSize           int
+		head, errs, tail  []line
+		headSize, errSize int
+		tailSize          int
+		inHead            = true
+		prev              *line
+	)
+
+	// reduce files l into head, or once that's full, into the tail. Lines
+	// pushed out of the tail are kept as errors if they look like one.
+	reduce := func(l line) {
+		if len(l.text) > maxLineBytes {
+			l.text = l.text[:maxLineBytes] + " [line cut]"
+		}
+		size := l.size(false)
+		if inHead {
+			if headSize+size <= headBudget {
+				head = append(head, l)
+				headSize += size
+				return
+			}
+			inHead = false
+		}
+		tail = append(tail, l)
+		tailSize += size
+		for tailSize > tailBudget && len(tail) > 1 {
+			out := tail[0]
+			tail = tail[1:]
+			tailSize -= out.size(false)
+			if errSize+out.size(false) <= errBudget && isError(out.text) {
+				errs = append(errs, out)
+				errSize += out.size(false)
+			}
+		}
+	}
+	keep := func(l line) {
+		if all == nil {
+			reduce(l)
+			return
+		}
+		all = append(all, l)
+		allSize += l.size(true)
+		if allSize > maxBytes {
+			for _, l := range all {
+				reduce(l)
+			}
+			all = nil
+		}
+	}
+	all = []line{}
+
+	br := bufio.NewReaderSize(r, 64*1024)
+	for {
+		text, err := readLine(br, maxBytes)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", stats, err
+		}
+		stats.Lines++
+
+		if prev != nil && prev.text == text {
+			prev.repeats++
+			continue
+		}
+		if prev != nil {
+			keep(*prev)
+		}
+		prev = &line{n: stats.Lines, text: text}
+	}
+	if prev != nil {
+		keep(*prev)
+	}
+
+	// Write out what was kept, marking the gaps
+	var b strings.Builder
+	next := 1
+	write := func(lines []line, expand bool) {
+		for _, l := range lines {
+			if gap := l.n - next; gap > 0 {
+				fmt.Fprintf(&b, "[... %s lines trimmed ...]\n", thousands(gap))
+			}
+			b.WriteString(l.render(expand))
+			b.WriteByte('\n')
+			if l.collapsed(expand) {
+				stats.Kept++
+				stats.Repeated += l.repeats
+			} else {
+				stats.Kept += l.repeats + 1
+			}
+			next = l.n + l.repeats + 1
+		}
+	}
+	if all != nil {
+		// It all fits
+		write(all, true)
+		return strings.TrimSuffix(b.String(), "\n"), stats, nil
+	}
+	write(head, false)
+	write(errs, false)
+	write(tail, false)
+	if gap := stats.Lines + 1 - next; gap > 0 {
+		fmt.Fprintf(&b, "[... %s lines trimmed ...]\n", thousands(gap))
+	}
+	return strings.TrimSuffix(b.String(), "\n"), stats, nil
+}
+
+// readLine reads the next line without its newline, cutting it at limit.
+func readLine(br *bufio.Reader, limit int) (string, error) {
+	var b []byte
+	cut := false
+	for {
+		chunk, isPrefix, err := br.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		if !cut {
+			b = append(b, chunk...)
+			if len(b) > limit {
+				b = append(b[:limit], " [line cut]"...)
+				cut = true
+			}
+		}
+		if !isPrefix {
+			return string(b), nil
+		}
+	}
+}
+
+// ReduceString is Reduce for content that is already in memory.
+func ReduceString(content string, maxBytes int) (string, Stats) {
+	reduced, stats, _ := Reduce(strings.NewReader(content), maxBytes)
+	return reduced, stats
+}
+
+// ReadFile reads the file at path through Reduce.
+func ReadFile(path string, maxBytes int) (string, Stats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", Stats{}, err
+	}
+	defer f.Close()
+	return Reduce(f, maxBytes)
+}
+
+// Describe names an attachment for the TUI header, with what was trimmed,
+// e.g. "build.log (kept 412 of 2,000,000 lines)".
+func Describe(name string, s Stats) string {
+	if !s.Trimmed() {
+		return name
+	}
+	return name + " (" + s.String() + ")"
+}
+
+// thousands formats n with comma separators.
+func thousands(n int) string {
+	s := fmt.Sprint(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
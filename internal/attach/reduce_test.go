@@ -0,0 +1,145 @@
+package attach
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// logLines generates a log of n lines, with an error at line errAt.
+func logLines(n, errAt int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		if i == errAt {
+			fmt.Fprintf(&b, "%05d ERROR connection refused by db:5432\n", i)
+			continue
+		}
+		fmt.Fprintf(&b, "%05d INFO request handled in %dms\n", i, i%97)
+	}
+	return b.String()
+}
+
+func TestReduce(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		maxBytes  int
+		want      []string // Must be in the output, in this order
+		notWant   []string
+		wantStats string
+	}{
+		{
+			name:     "fits unchanged",
+			input:    "a\n\n\nb\nb\n",
+			maxBytes: 1000,
+			want:     []string{"a\n\n\nb\nb"},
+		},
+		{
+			name:     "head, error and tail",
+			input:    logLines(10000, 5000),
+			maxBytes: 4000,
+			want: []string{
+				"00001 INFO",
+				"lines trimmed ...]",
+				"05000 ERROR connection refused",
+				"lines trimmed ...]",
+				"10000 INFO",
+			},
+			notWant:   []string{"05001 INFO"},
+			wantStats: "kept ",
+		},
+		{
+			name:     "repeats collapsed",
+			input:    "start\n" + strings.Repeat("retrying...\n", 5000) + logLines(200, 0),
+			maxBytes: 8000,
+			want: []string{
+				"start\nretrying...\n[... previous line repeated 4999 times ...]\n00001 INFO",
+				"00200 INFO",
+			},
+			wantStats: "kept ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stats, err := Reduce(strings.NewReader(tt.input), tt.maxBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) > tt.maxBytes+200 {
+				t.Errorf("Reduce() returned %d bytes, budget %d", len(got), tt.maxBytes)
+			}
+			rest := got
+			for _, w := range tt.want {
+				i := strings.Index(rest, w)
+				if i < 0 {
+					t.Fatalf("Reduce() is missing %q (in order):\n%s", w, got)
+				}
+				rest = rest[i+len(w):]
+			}
+			for _, nw := range tt.notWant {
+				if strings.Contains(got, nw) {
+					t.Errorf("Reduce() kept %q", nw)
+				}
+			}
+			if !strings.HasPrefix(stats.String(), tt.wantStats) || (tt.wantStats == "") != !stats.Trimmed() {
+				t.Errorf("Stats = %q (trimmed %v), want prefix %q", stats, stats.Trimmed(), tt.wantStats)
+			}
+		})
+	}
+}
+
+func TestReduceLongLines(t *testing.T) {
+	long := strings.Repeat("x", 10000)
+
+	// A long line that fits stays whole
+	got, _, _ := Reduce(strings.NewReader(long), 20000)
+	if got != long {
+		t.Errorf("Reduce() changed a line that fits (%d bytes)", len(got))
+	}
+
+	got, stats, _ := Reduce(strings.NewReader(long+"\n"+long+"y\n"+long+"z\n"), 6000)
+	if !strings.Contains(got, "[line cut]") || len(got) > 6000 {
+		t.Errorf("Reduce() = %d bytes, want cut lines within budget", len(got))
+	}
+	if stats.Lines != 3 {
+		t.Errorf("Lines = %d, want 3", stats.Lines)
+	}
+}
+
+// endless is a reader that never runs out of log lines, to show Reduce
+// doesn't hold the whole input.
+type endless struct {
+	lines, max int
+	buf        []byte
+}
+
+func (e *endless) Read(p []byte) (int, error) {
+	if len(e.buf) == 0 {
+		if e.lines == e.max {
+			return 0, io.EOF
+		}
+		e.lines++
+		e.buf = []byte(fmt.Sprintf("%d some log line\n", e.lines))
+	}
+	n := copy(p, e.buf)
+	e.buf = e.buf[n:]
+	return n, nil
+}
+
+func TestReduceLargeInput(t *testing.T) {
+	got, stats, err := Reduce(&endless{max: 500000}, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Lines != 500000 || len(got) > 16000 {
+		t.Errorf("Reduce() = %d bytes from %d lines", len(got), stats.Lines)
+	}
+	if !strings.HasSuffix(got, "500000 some log line") {
+		t.Errorf("Reduce() lost the end of the input")
+	}
+	if s := stats.String(); !strings.HasSuffix(s, "of 500,000 lines") {
+		t.Errorf("Stats = %q", s)
+	}
+}
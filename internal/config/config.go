@@ -2,9 +2,13 @@ package config
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -17,20 +21,42 @@ type ProviderConfig struct {
 	Params map[string]string `mapstructure:"params" yaml:"params"`
 }
 
+// CacheConfig controls the on-disk response cache. It is off by default.
+type CacheConfig struct {
+	Enabled   bool   `mapstructure:"enabled" yaml:"enabled"`
+	TTL       string `mapstructure:"ttl" yaml:"ttl"`                 // e.g. "24h"
+	MaxSizeMB int    `mapstructure:"max_size_mb" yaml:"max_size_mb"` // Oldest answers are dropped beyond this
+}
+
+// RedactConfig controls masking secrets in what is sent to providers that
+// don't run locally. It is on by default.
+type RedactConfig struct {
+	Enabled  bool     `mapstructure:"enabled" yaml:"enabled"`
+	Local    bool     `mapstructure:"local" yaml:"local"`       // Also redact for Ollama and localhost servers
+	Patterns []string `mapstructure:"patterns" yaml:"patterns"` // Extra regular expressions to mask
+}
+
 type Config struct {
 	DefaultProvider string                    `mapstructure:"default_provider" yaml:"default_provider"`
+	Fallback        []string                  `mapstructure:"fallback" yaml:"fallback"`
 	SystemPrompt    string                    `mapstructure:"system_prompt" yaml:"system_prompt"`
 	Context         map[string]string         `mapstructure:"context" yaml:"context"`
 	Providers       map[string]ProviderConfig `mapstructure:"providers" yaml:"providers"`
+	Cache           CacheConfig               `mapstructure:"cache" yaml:"cache"`
+	Redact          RedactConfig              `mapstructure:"redact" yaml:"redact"`
 }
 
 var AppConfig Config
 
-func Init() {
+// Init loads the config file, creating a default one if there is none.
+// AppConfig falls back to the defaults when the file can't be read, the
+// error says why.
+func Init() error {
+	var errs []error
 	configPath, err := GetConfigLocation()
 	huhDir := ""
 	if err != nil {
-		fmt.Printf("Error getting config location: %v\n", err)
+		errs = append(errs, err)
 		huhDir = "huh"
 		configPath = filepath.Join(huhDir, "config.yaml")
 	} else {
@@ -46,36 +72,106 @@ func Init() {
 	viper.SetDefault("default_provider", "ollama")
 	viper.SetDefault("context", map[string]string{"level": "basic"})
 	viper.SetDefault("system_prompt", "") // Default handled in code if empty
+	viper.SetDefault("redact.enabled", true)
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
 			// Config file not found; create default
-			createDefaultConfig(configPath)
-			if err := viper.ReadInConfig(); err != nil {
-				fmt.Printf("Error reading newly created config file: %s\n", err)
+			if err := createDefaultConfig(configPath); err != nil {
+				errs = append(errs, err)
+			} else if err := viper.ReadInConfig(); err != nil {
+				errs = append(errs, fmt.Errorf("reading newly created config file: %w", err))
 			}
 		} else {
-			fmt.Printf("Error reading config file: %s\n", err)
+			errs = append(errs, fmt.Errorf("reading config file: %w", err))
+		}
+	}
+	var invalid error
+	if path := viper.ConfigFileUsed(); len(errs) == 0 && path != "" {
+		invalid = validateFile(path)
+		if invalid != nil {
+			errs = append(errs, invalid)
+		}
+	}
+
+	// A value of the wrong type fails decoding too, the validation
+	// already says which one and on what line.
+	if err := viper.Unmarshal(&AppConfig); err != nil && invalid == nil {
+		errs = append(errs, fmt.Errorf("decoding config: %w", err))
+	}
+	// Viper lowercases the provider keys but not the values naming them
+	AppConfig.DefaultProvider = strings.ToLower(AppConfig.DefaultProvider)
+	for i, name := range AppConfig.Fallback {
+		AppConfig.Fallback[i] = strings.ToLower(name)
+	}
+	return errors.Join(errs...)
+}
+
+// validateFile checks the config file at path, see Validate.
+func validateFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if issues := Validate(data); len(issues) > 0 {
+		return &ValidationError{Path: path, Issues: issues}
+	}
+	return nil
+}
+
+// Override makes provider the default and model its model, for this run
+// only. Empty values leave the config as it is.
+func Override(provider, model string) error {
+	if provider != "" {
+		if _, ok := AppConfig.Providers[provider]; !ok {
+			return fmt.Errorf("provider '%s' not found in configuration (configured: %s)", provider, strings.Join(providerNames(), ", "))
 		}
+		AppConfig.DefaultProvider = provider
 	}
+	if model != "" {
+		if !setParam(AppConfig.DefaultProvider, "model", model) {
+			return fmt.Errorf("provider '%s' not found in configuration", AppConfig.DefaultProvider)
+		}
+	}
+	return nil
+}
 
-	if err := viper.Unmarshal(&AppConfig); err != nil {
-		fmt.Printf("Unable to decode into struct: %v\n", err)
+// setParam sets a param of the named provider in AppConfig, reporting
+// whether the provider exists.
+func setParam(provider, key, value string) bool {
+	p, ok := AppConfig.Providers[provider]
+	if !ok {
+		return false
 	}
+	// Copied, the map may be shared with viper
+	params := maps.Clone(p.Params)
+	if params == nil {
+		params = map[string]string{}
+	}
+	params[key] = value
+	p.Params = params
+	AppConfig.Providers[provider] = p
+	return true
+}
+
+func providerNames() []string {
+	names := slices.Collect(maps.Keys(AppConfig.Providers))
+	slices.Sort(names)
+	return names
 }
 
-func createDefaultConfig(path string) {
+func createDefaultConfig(path string) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		fmt.Printf("Error creating config directory: %v\n", err)
-		return
+		return fmt.Errorf("creating config directory: %w", err)
 	}
 
-	if err := os.WriteFile(path, defaultConfigFile, 0644); err != nil {
-		fmt.Printf("Error writing default config file: %v\n", err)
-		return
+	if err := writeConfig(path, defaultConfigFile, 0644); err != nil {
+		return fmt.Errorf("writing default config file: %w", err)
 	}
-	fmt.Printf("Created default config file at %s\n", path)
+	// Not on stdout, where it would end up in --print and --json output
+	fmt.Fprintf(os.Stderr, "Created default config file at %s\n", path)
+	return nil
 }
 
 func GetConfigLocation() (string, error) {
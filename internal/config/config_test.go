@@ -0,0 +1,122 @@
+package config
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestOverride(t *testing.T) {
+	saved := AppConfig
+	t.Cleanup(func() { AppConfig = saved })
+
+	tests := []struct {
+		name          string
+		provider      string
+		model         string
+		wantDefault   string
+		wantModel     string // Of the resulting default provider
+		wantErr       bool
+		wantUntouched string // Provider whose model must not change
+	}{
+		{"nothing", "", "", "ollama", "llama3:8b", false, ""},
+		{"provider", "openai", "", "openai", "gpt-4o", false, "ollama"},
+		{"model", "", "qwen2.5-coder:7b", "ollama", "qwen2.5-coder:7b", false, "openai"},
+		{"both", "openai", "gpt-4o-mini", "openai", "gpt-4o-mini", false, "ollama"},
+		{"no params yet", "bare", "m1", "bare", "m1", false, ""},
+		{"unknown provider", "nope", "", "ollama", "llama3:8b", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AppConfig = Config{
+				DefaultProvider: "ollama",
+				Providers: map[string]ProviderConfig{
+					"ollama": {Type: "ollama", Params: map[string]string{"model": "llama3:8b"}},
+					"openai": {Type: "openai", Params: map[string]string{"model": "gpt-4o"}},
+					"bare":   {Type: "ollama"},
+				},
+			}
+			untouched := AppConfig.Providers[tt.wantUntouched].Params["model"]
+
+			err := Override(tt.provider, tt.model)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Override() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if AppConfig.DefaultProvider != tt.wantDefault {
+				t.Errorf("DefaultProvider = %q, want %q", AppConfig.DefaultProvider, tt.wantDefault)
+			}
+			if got := AppConfig.Providers[AppConfig.DefaultProvider].Params["model"]; got != tt.wantModel {
+				t.Errorf("model = %q, want %q", got, tt.wantModel)
+			}
+			if got := AppConfig.Providers[tt.wantUntouched].Params["model"]; got != untouched {
+				t.Errorf("model of %s changed to %q", tt.wantUntouched, got)
+			}
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	saved := AppConfig
+	t.Cleanup(func() {
+		AppConfig = saved
+		viper.Reset()
+	})
+
+	// A missing config is created from the example, without a word on
+	// stdout that would mix with --json output
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	viper.Reset()
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	err = Init()
+	os.Stdout = stdout
+	w.Close()
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if out, _ := io.ReadAll(r); len(out) > 0 {
+		t.Errorf("Init() printed %q on stdout", out)
+	}
+	path, _ := GetConfigLocation()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Init() didn't create %s: %v", path, err)
+	}
+
+	// A broken one is reported, the defaults still apply
+	if err := os.WriteFile(path, []byte("providers: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	AppConfig = Config{}
+	if err := Init(); err == nil {
+		t.Error("Init() accepted a broken config file")
+	}
+	if AppConfig.DefaultProvider != "ollama" {
+		t.Errorf("DefaultProvider = %q, want the default", AppConfig.DefaultProvider)
+	}
+
+	// Provider names match the lowercased keys whatever their case
+	config := "default_provider: MyLocal\nfallback: [Ollama]\nproviders:\n  MyLocal:\n    type: ollama\n  ollama:\n    type: ollama\n"
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	AppConfig = Config{}
+	if err := Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if _, ok := AppConfig.Providers[AppConfig.DefaultProvider]; !ok {
+		t.Errorf("DefaultProvider %q is not in %v", AppConfig.DefaultProvider, AppConfig.Providers)
+	}
+	if len(AppConfig.Fallback) != 1 || AppConfig.Fallback[0] != "ollama" {
+		t.Errorf("Fallback = %v, want [ollama]", AppConfig.Fallback)
+	}
+}
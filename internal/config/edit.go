@@ -0,0 +1,102 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+// SetProviderParam sets a param of the named provider in the config file,
+// keeping the rest of the file and its comments, and in AppConfig.
+func SetProviderParam(provider, key, value string) error {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		var err error
+		if path, err = GetConfigLocation(); err != nil {
+			return err
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	updated, err := setProviderParam(data, provider, key, value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := writeConfig(path, updated, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	setParam(provider, key, value)
+	return nil
+}
+
+// setProviderParam edits the YAML in data through its node tree, so
+// comments and ordering survive.
+func setProviderParam(data []byte, provider, key, value string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping at the top level")
+	}
+
+	providers := mappingValue(doc.Content[0], "providers")
+	if providers == nil || providers.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("no providers section")
+	}
+	p := mappingValue(providers, provider)
+	if p == nil || p.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("provider '%s' not found", provider)
+	}
+	params := mappingValue(p, "params")
+	if params == nil || params.Kind != yaml.MappingNode {
+		if params == nil {
+			params = &yaml.Node{}
+			p.Content = append(p.Content, scalar("params"), params)
+		}
+		*params = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	if v := mappingValue(params, key); v != nil {
+		// Only the value changes, its comments stay
+		v.Kind, v.Tag, v.Style, v.Value = yaml.ScalarNode, "!!str", 0, value
+	} else {
+		params.Content = append(params.Content, scalar(key), scalar(value))
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mappingValue returns the value of key in the mapping node m. Keys are
+// matched case-insensitively, like viper does.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if strings.EqualFold(m.Content[i].Value, key) {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetProviderParam(t *testing.T) {
+	const file = `# My config
+default_provider: ollama
+providers:
+  ollama:
+    type: ollama # Local
+    params:
+      host: http://localhost:11434
+      model: llama3:8b # Too big for this laptop
+  bare:
+    type: ollama
+`
+	tests := []struct {
+		name     string
+		provider string
+		key      string
+		value    string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "replace",
+			provider: "ollama", key: "model", value: "qwen2.5-coder:7b",
+			want: []string{"# My config\n", "type: ollama # Local\n", "      model: qwen2.5-coder:7b # Too big for this laptop\n"},
+		},
+		{
+			name:     "add",
+			provider: "ollama", key: "timeout", value: "90s",
+			want: []string{"      model: llama3:8b # Too big for this laptop\n      timeout: 90s\n"},
+		},
+		{
+			name:     "no params yet",
+			provider: "bare", key: "model", value: "llama3",
+			want: []string{"  bare:\n    type: ollama\n    params:\n      model: llama3\n"},
+		},
+		{
+			name:     "unknown provider",
+			provider: "nope", key: "model", value: "x",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setProviderParam([]byte(file), tt.provider, tt.key, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setProviderParam() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(string(got), w) {
+					t.Errorf("setProviderParam() is missing %q:\n%s", w, got)
+				}
+			}
+		})
+	}
+}
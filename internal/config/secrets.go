@@ -0,0 +1,212 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// keyringService is the Secret Service attribute huh's keys are stored
+// under, next to an "account" attribute naming the key.
+const keyringService = "huh"
+
+// secretTimeout bounds api_key_cmd and keyring lookups, which may wait
+// for a passphrase prompt.
+const secretTimeout = time.Minute
+
+// envRef matches ${VAR} references in params.
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandEnv replaces the ${VAR} references in v with their environment
+// variables. Bare $VAR is left alone, passwords and URLs may contain
+// dollars.
+func ExpandEnv(v string) (string, error) {
+	var missing []string
+	expanded := envRef.ReplaceAllStringFunc(v, func(ref string) string {
+		name := envRef.FindStringSubmatch(ref)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// ResolveParams returns a copy of params with ${VAR} references expanded
+// and, if api_key isn't set, the key from api_key_cmd or api_key_keyring.
+func ResolveParams(params map[string]string) (map[string]string, error) {
+	resolved := maps.Clone(params)
+	if resolved == nil {
+		resolved = map[string]string{}
+	}
+	for k, v := range resolved {
+		expanded, err := ExpandEnv(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		resolved[k] = expanded
+	}
+
+	if resolved["api_key"] != "" {
+		return resolved, nil
+	}
+	switch KeySource(params) {
+	case "api_key_cmd":
+		key, err := runKeyCmd(resolved["api_key_cmd"])
+		if err != nil {
+			return nil, fmt.Errorf("api_key_cmd: %w", err)
+		}
+		resolved["api_key"] = key
+	case "api_key_keyring":
+		key, err := keyringLookup(resolved["api_key_keyring"])
+		if err != nil {
+			return nil, fmt.Errorf("api_key_keyring: %w", err)
+		}
+		resolved["api_key"] = key
+	}
+	return resolved, nil
+}
+
+// KeySource says where the API key in params comes from: "api_key",
+// "api_key_cmd", "api_key_keyring", or "" if there is none.
+func KeySource(params map[string]string) string {
+	for _, k := range []string{"api_key", "api_key_cmd", "api_key_keyring"} {
+		if params[k] != "" {
+			return k
+		}
+	}
+	return ""
+}
+
+// keyCache holds the output of each api_key_cmd, so a password manager is
+// asked once per run.
+var keyCache = struct {
+	sync.Mutex
+	keys map[string]string
+}{keys: map[string]string{}}
+
+// runKeyCmd runs command in the shell and returns the first line it
+// prints, like `pass show openai` does.
+func runKeyCmd(command string) (string, error) {
+	keyCache.Lock()
+	defer keyCache.Unlock()
+	if key, ok := keyCache.keys[command]; ok {
+		return key, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+	// Stdin is left alone, it may hold piped attachments. Password
+	// managers prompt through the terminal or a pinentry instead.
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	key, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	if key == "" {
+		return "", fmt.Errorf("%q printed nothing", command)
+	}
+	keyCache.keys[command] = key
+	return key, nil
+}
+
+// keyringLookup reads the key stored for account in the Secret Service
+// keyring (GNOME Keyring, KWallet, KeePassXC), through secret-tool.
+func keyringLookup(account string) (string, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return "", fmt.Errorf("secret-tool not found, install libsecret-tools or use api_key_cmd")
+	}
+	key, err := runKeyCmd(fmt.Sprintf("secret-tool lookup service %s account %s", keyringService, shellQuote(account)))
+	if err != nil {
+		return "", fmt.Errorf("no key for account %q (store one with: secret-tool store --label=huh service %s account %s): %w",
+			account, keyringService, shellQuote(account), err)
+	}
+	return key, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// hasLiteralKey reports whether the config file in data holds an API key
+// as plain text rather than a ${VAR} reference, either as api_key or in a
+// header like header_authorization.
+func hasLiteralKey(data []byte) bool {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return false
+	}
+	for _, p := range cfg.Providers {
+		for param, value := range p.Params {
+			if isKeyParam(param) && value != "" && !envRef.MatchString(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isKeyParam reports whether param carries a credential: api_key, or a
+// header whose name says it's one.
+func isKeyParam(param string) bool {
+	param = strings.ToLower(param)
+	header, ok := strings.CutPrefix(param, HeaderParamPrefix)
+	if !ok {
+		return param == "api_key"
+	}
+	for _, word := range []string{"auth", "key", "token", "secret"} {
+		if strings.Contains(header, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileMode is the permissions a config file with data gets: only the
+// user may read one that holds a key.
+func fileMode(data []byte, mode os.FileMode) os.FileMode {
+	if hasLiteralKey(data) {
+		return mode &^ 0o077
+	}
+	return mode
+}
+
+// LiteralKeyExposed reports whether the config file at path holds an API
+// key and others can read it.
+func LiteralKeyExposed(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm()&0o077 == 0 {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	return err == nil && hasLiteralKey(data)
+}
+
+// writeConfig writes a config file, tightening mode if it holds a key.
+// Existing files get the mode too, WriteFile only sets it on new ones.
+func writeConfig(path string, data []byte, mode os.FileMode) error {
+	mode = fileMode(data, mode)
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveParams(t *testing.T) {
+	t.Setenv("HUH_TEST_KEY", "sk-from-env")
+	t.Setenv("HUH_TEST_HOST", "gpu-box")
+	counter := filepath.Join(t.TempDir(), "runs")
+
+	tests := []struct {
+		name    string
+		params  map[string]string
+		wantKey string
+		wantErr string
+	}{
+		{"literal", map[string]string{"api_key": "sk-literal"}, "sk-literal", ""},
+		{"env", map[string]string{"api_key": "${HUH_TEST_KEY}"}, "sk-from-env", ""},
+		{"unset env", map[string]string{"api_key": "${HUH_TEST_UNSET}"}, "", "HUH_TEST_UNSET is not set"},
+		{"bare dollar kept", map[string]string{"api_key": "pa$HUH_TEST_KEY"}, "pa$HUH_TEST_KEY", ""},
+		{"command", map[string]string{"api_key_cmd": "echo run >> " + counter + "; printf 'sk-from-cmd\\nsecond line\\n'"}, "sk-from-cmd", ""},
+		{"command fails", map[string]string{"api_key_cmd": "echo locked >&2; exit 1"}, "", "locked"},
+		{"command prints nothing", map[string]string{"api_key_cmd": "true"}, "", "printed nothing"},
+		{"api_key wins", map[string]string{"api_key": "sk-literal", "api_key_cmd": "exit 1"}, "sk-literal", ""},
+		{"no key", map[string]string{"model": "m"}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveParams(tt.params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ResolveParams() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got["api_key"] != tt.wantKey {
+				t.Errorf("api_key = %q, want %q", got["api_key"], tt.wantKey)
+			}
+		})
+	}
+
+	// Other params are expanded too, and the originals stay as they are
+	params := map[string]string{"host": "http://${HUH_TEST_HOST}:11434"}
+	got, _ := ResolveParams(params)
+	if got["host"] != "http://gpu-box:11434" || params["host"] != "http://${HUH_TEST_HOST}:11434" {
+		t.Errorf("host = %q, original %q", got["host"], params["host"])
+	}
+
+	// The command ran once, the second lookup came from the cache
+	if _, err := ResolveParams(tests[4].params); err != nil {
+		t.Fatal(err)
+	}
+	runs, _ := os.ReadFile(counter)
+	if n := strings.Count(string(runs), "run"); n != 1 {
+		t.Errorf("api_key_cmd ran %d times, want 1", n)
+	}
+}
+
+func TestWriteConfigMode(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want os.FileMode
+	}{
+		{"no key", "providers:\n  ollama:\n    type: ollama\n", 0o644},
+		{"env reference", "providers:\n  openai:\n    params:\n      api_key: ${OPENAI_API_KEY}\n", 0o644},
+		{"command", "providers:\n  openai:\n    params:\n      api_key_cmd: pass show openai\n", 0o644},
+		{"literal key", "providers:\n  openai:\n    params:\n      api_key: sk-proj-123\n", 0o600},
+		{"literal header", "providers:\n  local:\n    params:\n      header_authorization: Bearer sk-proj-123\n", 0o600},
+		{"literal header key", "providers:\n  local:\n    params:\n      Header_X-API-Key: sk-proj-123\n", 0o600},
+		{"header reference", "providers:\n  local:\n    params:\n      header_authorization: Bearer ${TOKEN}\n", 0o644},
+		{"other header", "providers:\n  local:\n    params:\n      header_x-source: huh\n", 0o644},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			// Written twice, an existing file has to be tightened too
+			for range 2 {
+				if err := writeConfig(path, []byte(tt.data), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != tt.want {
+				t.Errorf("mode = %o, want %o", got, tt.want)
+			}
+			if LiteralKeyExposed(path) {
+				t.Errorf("LiteralKeyExposed() = true after writeConfig")
+			}
+			// As if the user had copied it from a dotfile repo
+			os.Chmod(path, 0o644)
+			if want := tt.want == 0o600; LiteralKeyExposed(path) != want {
+				t.Errorf("LiteralKeyExposed() = %v, want %v", !want, want)
+			}
+		})
+	}
+}
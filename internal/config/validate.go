@@ -0,0 +1,386 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ProviderTypes are the supported values of a provider's type.
+var ProviderTypes = []string{"ollama", "openai", "anthropic", "openrouter", "openai_compatible"}
+
+// Known keys, kept in sync with config.schema.json by the tests
+var (
+	topKeys      = []string{"default_provider", "fallback", "system_prompt", "context", "providers", "cache", "redact"}
+	providerKeys = []string{"type", "params"}
+	cacheKeys    = []string{"enabled", "ttl", "max_size_mb"}
+	redactKeys   = []string{"enabled", "local", "patterns"}
+
+	// Params every provider type accepts
+	commonParams = []string{"model", "timeout", "max_retries", "context_window"}
+	keyParams    = []string{"api_key", "api_key_cmd", "api_key_keyring"}
+	openAIParams = append(append([]string{"base_url", "organization", "project"}, commonParams...), keyParams...)
+
+	typeParams = map[string][]string{
+		"ollama":            append([]string{"host"}, commonParams...),
+		"openai":            openAIParams,
+		"openrouter":        openAIParams,
+		"openai_compatible": openAIParams,
+		"anthropic":         append(append([]string{"base_url", "max_tokens"}, commonParams...), keyParams...),
+	}
+
+	// Types that send header_ params as HTTP headers
+	headerTypes = []string{"openai", "openrouter", "openai_compatible"}
+)
+
+// Issue is one problem with the config file.
+type Issue struct {
+	Line    int // 0 if it isn't tied to a line
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.Line == 0 {
+		return i.Message
+	}
+	return fmt.Sprintf("line %d: %s", i.Line, i.Message)
+}
+
+// ValidationError lists everything wrong with the config file at Path.
+type ValidationError struct {
+	Path   string
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid config %s:", e.Path)
+	for _, i := range e.Issues {
+		b.WriteString("\n  " + i.String())
+	}
+	return b.String()
+}
+
+// Validate checks the config file in data for unknown keys, unknown
+// provider types, missing providers and missing or malformed params. Keys
+// are matched case-insensitively, like viper does.
+func Validate(data []byte) []Issue {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []Issue{{Message: err.Error()}}
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	v := &validator{}
+	v.config(doc.Content[0])
+	slices.SortStableFunc(v.issues, func(a, b Issue) int { return a.Line - b.Line })
+	return v.issues
+}
+
+type validator struct {
+	issues []Issue
+}
+
+func (v *validator) add(n *yaml.Node, format string, args ...any) {
+	v.issues = append(v.issues, Issue{Line: n.Line, Message: fmt.Sprintf(format, args...)})
+}
+
+// fields returns the values of mapping m by lowercased key, reporting
+// keys that aren't in known. section names m in messages.
+func (v *validator) fields(m *yaml.Node, section string, known []string) map[string]*yaml.Node {
+	if m.Kind != yaml.MappingNode {
+		v.add(m, "%s should be a mapping", section)
+		return nil
+	}
+	fields := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		key := m.Content[i]
+		name := strings.ToLower(key.Value)
+		if !slices.Contains(known, name) {
+			v.add(key, "unknown key %q in %s%s", key.Value, section, suggestion(name, known))
+			continue
+		}
+		fields[name] = m.Content[i+1]
+	}
+	return fields
+}
+
+func (v *validator) config(root *yaml.Node) {
+	fields := v.fields(root, "the config", topKeys)
+	if fields == nil {
+		return
+	}
+
+	var names []string
+	if providers := fields["providers"]; providers != nil {
+		names = v.providers(providers)
+	}
+
+	defaultProvider := "ollama" // viper's default
+	if n := fields["default_provider"]; n != nil && v.scalar(n, "default_provider") {
+		// Provider keys are lowercased like every viper key, names match
+		// them whatever their case
+		defaultProvider = strings.ToLower(n.Value)
+		if !slices.Contains(names, defaultProvider) {
+			v.add(n, "default_provider %q is not in providers%s", n.Value, suggestion(n.Value, names))
+		}
+	} else if n == nil && !slices.Contains(names, defaultProvider) {
+		v.issues = append(v.issues, Issue{Message: fmt.Sprintf("default_provider is not set and there is no provider named %q", defaultProvider)})
+	}
+
+	if n := fields["fallback"]; n != nil {
+		if n.Kind != yaml.SequenceNode {
+			v.add(n, "fallback should be a list of provider names")
+		} else {
+			for _, fb := range n.Content {
+				if v.scalar(fb, "fallback entry") && !slices.Contains(names, strings.ToLower(fb.Value)) {
+					v.add(fb, "fallback provider %q is not in providers%s", fb.Value, suggestion(fb.Value, names))
+				}
+			}
+		}
+	}
+	if n := fields["system_prompt"]; n != nil {
+		v.scalar(n, "system_prompt")
+	}
+	if n := fields["context"]; n != nil {
+		v.context(n)
+	}
+	if n := fields["cache"]; n != nil {
+		v.cache(n)
+	}
+	if n := fields["redact"]; n != nil {
+		v.redact(n)
+	}
+}
+
+// providers checks each provider and returns their names.
+func (v *validator) providers(m *yaml.Node) []string {
+	if m.Kind != yaml.MappingNode {
+		v.add(m, "providers should be a mapping of names to providers")
+		return nil
+	}
+	var names []string
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		name := m.Content[i].Value
+		names = append(names, strings.ToLower(name))
+		v.provider(name, m.Content[i], m.Content[i+1])
+	}
+	return names
+}
+
+func (v *validator) provider(name string, key, n *yaml.Node) {
+	section := fmt.Sprintf("provider %q", name)
+	fields := v.fields(n, section, providerKeys)
+	if fields == nil {
+		return
+	}
+	typ := fields["type"]
+	if typ == nil {
+		v.add(key, "%s has no type (one of %s)", section, strings.Join(ProviderTypes, ", "))
+		return
+	}
+	if !v.scalar(typ, section+" type") {
+		return
+	}
+	known, ok := typeParams[typ.Value]
+	if !ok {
+		hint := suggestion(typ.Value, ProviderTypes)
+		if hint == "" {
+			hint = " (one of " + strings.Join(ProviderTypes, ", ") + ")"
+		}
+		v.add(typ, "%s has unknown type %q%s", section, typ.Value, hint)
+		return
+	}
+
+	params := map[string]*yaml.Node{}
+	if p := fields["params"]; p != nil {
+		if p.Kind != yaml.MappingNode {
+			v.add(p, "params of %s should be a mapping", section)
+			return
+		}
+		for i := 0; i+1 < len(p.Content); i += 2 {
+			k, val := p.Content[i], p.Content[i+1]
+			param := k.Value
+			header := strings.HasPrefix(strings.ToLower(param), HeaderParamPrefix) && slices.Contains(headerTypes, typ.Value)
+			if !header && !slices.Contains(known, strings.ToLower(param)) {
+				v.add(k, "unknown param %q for %s type %s%s", param, section, typ.Value, suggestion(strings.ToLower(param), known))
+				continue
+			}
+			if v.scalar(val, "param "+param) {
+				params[strings.ToLower(param)] = val
+				v.paramValue(strings.ToLower(param), val)
+			}
+		}
+	}
+
+	// Required params
+	switch typ.Value {
+	case "openai", "openrouter", "anthropic":
+		if params["api_key"] == nil && params["api_key_cmd"] == nil && params["api_key_keyring"] == nil {
+			v.add(typ, "%s needs api_key, api_key_cmd or api_key_keyring in its params", section)
+		}
+	case "openai_compatible":
+		if params["base_url"] == nil {
+			v.add(typ, "%s needs base_url in its params", section)
+		}
+	}
+}
+
+// HeaderParamPrefix marks params that OpenAI-compatible providers send as
+// extra HTTP headers, e.g. "header_x-request-source: huh".
+const HeaderParamPrefix = "header_"
+
+// paramValue checks the params that have to be numbers or durations.
+// Values from the environment are only known later.
+func (v *validator) paramValue(param string, n *yaml.Node) {
+	if strings.Contains(n.Value, "${") {
+		return
+	}
+	switch param {
+	case "timeout":
+		v.duration(n, param)
+	case "max_retries":
+		v.integer(n, param, 0)
+	case "context_window", "max_tokens":
+		v.integer(n, param, 1)
+	case "base_url", "host":
+		if !strings.HasPrefix(n.Value, "http://") && !strings.HasPrefix(n.Value, "https://") {
+			v.add(n, "%s %q should start with http:// or https://", param, n.Value)
+		}
+	}
+}
+
+func (v *validator) context(n *yaml.Node) {
+	if n.Kind != yaml.MappingNode {
+		v.add(n, "context should be a mapping")
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i], n.Content[i+1]
+		if !v.scalar(val, "context "+key.Value) {
+			continue
+		}
+		switch strings.ToLower(key.Value) {
+		case "level":
+			if val.Value != "basic" && val.Value != "hardware" {
+				v.add(val, "context level %q should be basic or hardware", val.Value)
+			}
+		case "git", "project":
+			v.boolean(val, "context "+key.Value)
+		}
+	}
+}
+
+func (v *validator) cache(n *yaml.Node) {
+	fields := v.fields(n, "cache", cacheKeys)
+	if f := fields["enabled"]; f != nil {
+		v.boolean(f, "cache enabled")
+	}
+	if f := fields["ttl"]; f != nil {
+		v.duration(f, "cache ttl")
+	}
+	if f := fields["max_size_mb"]; f != nil {
+		v.integer(f, "cache max_size_mb", 1)
+	}
+}
+
+func (v *validator) redact(n *yaml.Node) {
+	fields := v.fields(n, "redact", redactKeys)
+	if f := fields["enabled"]; f != nil {
+		v.boolean(f, "redact enabled")
+	}
+	if f := fields["local"]; f != nil {
+		v.boolean(f, "redact local")
+	}
+	if f := fields["patterns"]; f != nil {
+		if f.Kind != yaml.SequenceNode {
+			v.add(f, "redact patterns should be a list of regular expressions")
+			return
+		}
+		for _, p := range f.Content {
+			if !v.scalar(p, "redact pattern") {
+				continue
+			}
+			if _, err := regexp.Compile(p.Value); err != nil {
+				v.add(p, "redact pattern %q: %v", p.Value, err)
+			}
+		}
+	}
+}
+
+func (v *validator) scalar(n *yaml.Node, what string) bool {
+	if n.Kind != yaml.ScalarNode {
+		v.add(n, "%s should be a single value", what)
+		return false
+	}
+	return true
+}
+
+func (v *validator) boolean(n *yaml.Node, what string) {
+	if !v.scalar(n, what) {
+		return
+	}
+	if _, err := strconv.ParseBool(n.Value); err != nil {
+		v.add(n, "%s %q should be true or false", what, n.Value)
+	}
+}
+
+func (v *validator) integer(n *yaml.Node, what string, min int) {
+	if !v.scalar(n, what) {
+		return
+	}
+	if i, err := strconv.Atoi(n.Value); err != nil || i < min {
+		v.add(n, "%s %q should be a whole number of at least %d", what, n.Value, min)
+	}
+}
+
+func (v *validator) duration(n *yaml.Node, what string) {
+	if !v.scalar(n, what) {
+		return
+	}
+	if d, err := time.ParseDuration(n.Value); err != nil || d <= 0 {
+		v.add(n, "%s %q should be a duration like 30s or 24h", what, n.Value)
+	}
+}
+
+// suggestion returns `, did you mean "x"?` for the option closest to
+// word, if one is close enough to be a typo.
+func suggestion(word string, options []string) string {
+	best, bestDist := "", 3
+	for _, o := range options {
+		if d := editDistance(word, o); d < bestDist && d < len(o)/2+1 {
+			best, bestDist = o, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string // Issues, as "line N: message" substrings
+	}{
+		{"valid", `
+default_provider: local
+fallback: [ollama]
+providers:
+  local:
+    type: openai_compatible
+    params:
+      base_url: http://localhost:8000/v1
+      header_x-source: huh
+      timeout: 90s
+  ollama:
+    type: ollama
+    params:
+      host: ${OLLAMA_HOST}
+      context_window: 8192
+context:
+  level: hardware
+  editor: nano
+cache:
+  enabled: true
+  ttl: 24h
+`, nil},
+		{"empty", ``, nil},
+		{"typo key", `
+defualt_provider: ollama
+providers:
+  ollama:
+    type: ollama
+`, []string{`line 2: unknown key "defualt_provider" in the config, did you mean "default_provider"?`}},
+		{"unknown type", `
+providers:
+  ollama:
+    type: olama
+`, []string{`line 4: provider "ollama" has unknown type "olama", did you mean "ollama"?`}},
+		{"missing type", `
+providers:
+  ollama:
+    params:
+      model: llama3
+`, []string{`line 3: provider "ollama" has no type`}},
+		{"default provider missing", `
+default_provider: openai
+providers:
+  ollama:
+    type: ollama
+`, []string{`line 2: default_provider "openai" is not in providers`}},
+		{"default provider case", `
+default_provider: MyLocal
+fallback: [Ollama]
+providers:
+  MyLocal:
+    type: ollama
+  ollama:
+    type: ollama
+`, nil},
+		{"no providers", `
+system_prompt: hi
+`, []string{`default_provider is not set and there is no provider named "ollama"`}},
+		{"fallback missing", `
+fallback: [ollama, openrouter]
+providers:
+  ollama:
+    type: ollama
+`, []string{`line 2: fallback provider "openrouter" is not in providers`}},
+		{"required params", `
+default_provider: openai
+providers:
+  openai:
+    type: openai
+    params:
+      model: gpt-4o
+  local:
+    type: openai_compatible
+`, []string{
+			`line 5: provider "openai" needs api_key, api_key_cmd or api_key_keyring`,
+			`line 9: provider "local" needs base_url`,
+		}},
+		{"unknown param", `
+providers:
+  ollama:
+    type: ollama
+    params:
+      modle: llama3
+      header_x: y
+`, []string{
+			`line 6: unknown param "modle" for provider "ollama" type ollama, did you mean "model"?`,
+			`line 7: unknown param "header_x"`,
+		}},
+		{"anthropic params", `
+default_provider: anthropic
+providers:
+  anthropic:
+    type: anthropic
+    params:
+      api_key: ${ANTHROPIC_API_KEY}
+      base_url: https://gateway.example.com
+      header_x-source: huh
+`, []string{`line 9: unknown param "header_x-source"`}},
+		{"bad values", `
+providers:
+  ollama:
+    type: ollama
+    params:
+      host: localhost:11434
+      timeout: 30
+      max_retries: -1
+cache:
+  enabled: yes please
+  max_size_mb: 0
+redact:
+  patterns: ['corp-[']
+context:
+  level: full
+`, []string{
+			`line 6: host "localhost:11434" should start with http:// or https://`,
+			`line 7: timeout "30" should be a duration like 30s or 24h`,
+			`line 8: max_retries "-1" should be a whole number of at least 0`,
+			`line 10: cache enabled "yes please" should be true or false`,
+			`line 11: cache max_size_mb "0" should be a whole number of at least 1`,
+			`line 13: redact pattern "corp-["`,
+			`line 15: context level "full" should be basic or hardware`,
+		}},
+		{"wrong shape", `
+providers:
+  - ollama
+`, []string{
+			`default_provider is not set`,
+			`line 3: providers should be a mapping`,
+		}},
+		{"not yaml", `providers: [`, []string{`yaml:`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := Validate([]byte(tt.config))
+			if len(issues) != len(tt.want) {
+				t.Fatalf("Validate() = %q, want %d issues", issues, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got := issues[i].String(); !strings.Contains(got, want) {
+					t.Errorf("issue %d = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateDefaultConfig(t *testing.T) {
+	if issues := Validate(defaultConfigFile); len(issues) > 0 {
+		t.Errorf("config.example.yaml has issues: %q", issues)
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	err := &ValidationError{Path: "/x/config.yaml", Issues: []Issue{{Line: 2, Message: "a"}, {Message: "b"}}}
+	want := "invalid config /x/config.yaml:\n  line 2: a\n  b"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+// The schema is written by hand, check it knows the keys the validator
+// does.
+func TestSchemaMatchesValidator(t *testing.T) {
+	data, err := os.ReadFile("config.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type object struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	var schema struct {
+		object
+		Definitions struct {
+			Provider struct {
+				object
+				AllOf []struct {
+					If struct {
+						Properties struct {
+							Type struct {
+								Const string `json:"const"`
+							} `json:"type"`
+						} `json:"properties"`
+					} `json:"if"`
+					Then struct {
+						Properties struct {
+							Params object `json:"params"`
+						} `json:"properties"`
+					} `json:"then"`
+				} `json:"allOf"`
+			} `json:"provider"`
+		} `json:"definitions"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	keys := func(m map[string]json.RawMessage) []string {
+		return slices.Sorted(maps.Keys(m))
+	}
+
+	if got, want := keys(schema.Properties), slices.Sorted(slices.Values(topKeys)); !slices.Equal(got, want) {
+		t.Errorf("schema keys = %v, want %v", got, want)
+	}
+	for name, known := range map[string][]string{"cache": cacheKeys, "redact": redactKeys} {
+		var sub object
+		if err := json.Unmarshal(schema.Properties[name], &sub); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := keys(sub.Properties), slices.Sorted(slices.Values(known)); !slices.Equal(got, want) {
+			t.Errorf("schema %s keys = %v, want %v", name, got, want)
+		}
+	}
+
+	var types []string
+	for _, c := range schema.Definitions.Provider.AllOf {
+		typ := c.If.Properties.Type.Const
+		types = append(types, typ)
+		got, want := keys(c.Then.Properties.Params.Properties), slices.Sorted(slices.Values(typeParams[typ]))
+		if !slices.Equal(got, want) {
+			t.Errorf("schema params of %s = %v, want %v", typ, got, want)
+		}
+	}
+	if !slices.Equal(slices.Sorted(slices.Values(types)), slices.Sorted(slices.Values(ProviderTypes))) {
+		t.Errorf("schema provider types = %v, want %v", types, ProviderTypes)
+	}
+}
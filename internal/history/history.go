@@ -0,0 +1,175 @@
+// Package history keeps a log of past questions and answers so they can be
+// looked up again without asking the provider.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"huh/internal/config"
+)
+
+// Entry is one answered question.
+type Entry struct {
+	Time        time.Time `json:"time"`
+	Question    string    `json:"question"`
+	Refinements []string  `json:"refinements,omitempty"` // Follow-up changes asked for after Question, in order
+	Attachments []string  `json:"attachments,omitempty"`
+	Provider    string    `json:"provider"`
+	Model       string    `json:"model"`
+	Answer      string    `json:"answer"`
+	Commands    []string  `json:"commands,omitempty"`
+	Action      string    `json:"action,omitempty"` // What was done with the answer: copy, insert, run, print
+}
+
+// DefaultMax is how many entries a store keeps unless told otherwise.
+const DefaultMax = 1000
+
+// Store is a JSON Lines file of entries, oldest first.
+type Store struct {
+	Path string
+	Max  int // Entries kept, the oldest are dropped past it. 0 is DefaultMax
+}
+
+// DefaultStore returns the store next to the config file.
+func DefaultStore() (*Store, error) {
+	configPath, err := config.GetConfigLocation()
+	if err != nil {
+		return nil, err
+	}
+	return &Store{Path: filepath.Join(filepath.Dir(configPath), "history.jsonl")}, nil
+}
+
+// Append adds an entry to the end of the store.
+func (s *Store) Append(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return err
+	}
+	// Answers can quote attached files, so keep the log private
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return s.trim()
+}
+
+// trim drops the oldest entries past the store's maximum, so the file
+// doesn't grow forever. It is replaced in one go, a crash keeps the old one.
+func (s *Store) trim() error {
+	limit := s.Max
+	if limit <= 0 {
+		limit = DefaultMax
+	}
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return err
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= limit {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), ".history-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(bytes.Join(lines[len(lines)-limit:], nil)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+// Load returns all entries, newest first. A missing store is empty, and
+// lines that can't be parsed are skipped.
+func (s *Store) Load() ([]Entry, error) {
+	f, err := os.Open(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// Clear deletes every entry.
+func (s *Store) Clear() error {
+	err := os.Remove(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Search returns the entries whose question, commands or answer contain
+// every word of query, ignoring case. Order is kept.
+func Search(entries []Entry, query string) []Entry {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return entries
+	}
+
+	var matches []Entry
+	for _, e := range entries {
+		text := strings.ToLower(e.Question + "\n" + strings.Join(e.Refinements, "\n") + "\n" +
+			strings.Join(e.Commands, "\n") + "\n" + e.Answer)
+		found := true
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				found = false
+				break
+			}
+		}
+		if found {
+			matches = append(matches, e)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,119 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStoreAppendLoad(t *testing.T) {
+	s := &Store{Path: filepath.Join(t.TempDir(), "huh", "history.jsonl")}
+
+	entries, err := s.Load()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Load() on missing store = %v, %v", entries, err)
+	}
+
+	for _, q := range []string{"first", "second"} {
+		if err := s.Append(Entry{Question: q, Commands: []string{"echo " + q}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A broken line shouldn't lose the rest of the history
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{not json\n")
+	f.Close()
+
+	entries, err = s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Question != "second" || entries[1].Question != "first" {
+		t.Fatalf("Load() = %+v, want newest first", entries)
+	}
+	if entries[0].Time.IsZero() {
+		t.Error("Append() didn't set the time")
+	}
+
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("history file mode = %o, want 600", perm)
+	}
+
+	if err := s.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := s.Load(); len(entries) != 0 {
+		t.Errorf("Load() after Clear() = %+v", entries)
+	}
+}
+
+func TestStoreMax(t *testing.T) {
+	s := &Store{Path: filepath.Join(t.TempDir(), "history.jsonl"), Max: 3}
+	for i := range 5 {
+		if err := s.Append(Entry{Question: fmt.Sprint("q", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var questions []string
+	for _, e := range entries {
+		questions = append(questions, e.Question)
+	}
+	if want := []string{"q4", "q3", "q2"}; !slices.Equal(questions, want) {
+		t.Errorf("Load() after trimming = %v, want %v", questions, want)
+	}
+
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("trimmed history file mode = %o, want 600", perm)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	entries := []Entry{
+		{Time: time.Unix(3, 0), Question: "find large files", Commands: []string{"du -ah . | sort -h"}},
+		{Time: time.Unix(2, 0), Question: "check disk space", Commands: []string{"df -h"}},
+		{Time: time.Unix(1, 0), Question: "list open ports", Answer: "Use ss:\n```\nss -tlnp\n```"},
+		{Time: time.Unix(0, 0), Question: "show processes", Refinements: []string{"only mine"}},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"find large files", "check disk space", "list open ports", "show processes"}},
+		{"only mine", []string{"show processes"}},
+		{"DISK", []string{"check disk space"}},
+		{"sort", []string{"find large files"}},
+		{"ss -tlnp", []string{"list open ports"}},
+		{"files disk", nil},
+	}
+	for _, tt := range tests {
+		got := Search(entries, tt.query)
+		var questions []string
+		for _, e := range got {
+			questions = append(questions, e.Question)
+		}
+		if !slices.Equal(questions, tt.want) {
+			t.Errorf("Search(%q) = %q, want %q", tt.query, questions, tt.want)
+		}
+	}
+}
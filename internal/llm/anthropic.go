@@ -0,0 +1,212 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	anthropicBaseURL = "https://api.anthropic.com"
+	anthropicVersion = "2023-06-01"
+)
+
+type AnthropicProvider struct {
+	ProviderName string // Configured name
+	APIKey       string
+	Model        string
+	MaxTokens    int
+	BaseURL      string
+	Window       int // Context window in tokens, 0 looks the model up
+
+	http *transport
+}
+
+func NewAnthropicProvider(apiKey, model string, maxTokens int) *AnthropicProvider {
+	return &AnthropicProvider{
+		ProviderName: "anthropic",
+		APIKey:       apiKey,
+		Model:        model,
+		MaxTokens:    maxTokens,
+		BaseURL:      anthropicBaseURL,
+		http:         defaultTransport(),
+	}
+}
+
+func (a *AnthropicProvider) Name() string {
+	return a.ProviderName
+}
+
+func (a *AnthropicProvider) ModelName() string {
+	return a.Model
+}
+
+func (a *AnthropicProvider) ContextWindow() int {
+	if a.Window > 0 {
+		return a.Window
+	}
+	return modelContextWindow(a.Model)
+}
+
+type anthropicRequest struct {
+	Model     string    `json:"model"`
+	System    string    `json:"system,omitempty"`
+	Messages  []Message `json:"messages"`
+	MaxTokens int       `json:"max_tokens"`
+	Stream    bool      `json:"stream,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+}
+
+type anthropicErrorBody struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type anthropicStreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Error *anthropicErrorBody `json:"error"`
+}
+
+func (a *AnthropicProvider) Query(ctx context.Context, systemPrompt string, userQuery string) (string, error) {
+	return a.Chat(ctx, singleTurn(systemPrompt, userQuery))
+}
+
+func (a *AnthropicProvider) Stream(ctx context.Context, systemPrompt string, userQuery string, onChunk func(string)) (string, error) {
+	return a.ChatStream(ctx, singleTurn(systemPrompt, userQuery), onChunk)
+}
+
+// Models lists the models the API key can use, from /v1/models.
+func (a *AnthropicProvider) Models(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(a.BaseURL, "/")+"/v1/models?limit=1000", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-api-key", a.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	return listModels(a.http, req, "anthropic", a.Name())
+}
+
+func (a *AnthropicProvider) newRequest(ctx context.Context, messages []Message, stream bool) (*http.Request, error) {
+	// The Messages API takes the system prompt as a top-level field and only
+	// accepts user/assistant turns in the message list.
+	var system []string
+	var turns []Message
+	for _, m := range messages {
+		if m.Role == RoleSystem {
+			system = append(system, m.Content)
+			continue
+		}
+		turns = append(turns, m)
+	}
+
+	reqBody := anthropicRequest{
+		Model:     a.Model,
+		System:    strings.Join(system, "\n\n"),
+		Messages:  turns,
+		MaxTokens: a.MaxTokens,
+		Stream:    stream,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", a.BaseURL+"/v1/messages", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", a.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	return req, nil
+}
+
+func (a *AnthropicProvider) Chat(ctx context.Context, messages []Message) (string, error) {
+	req, err := a.newRequest(ctx, messages, false)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := a.http.do(req, "anthropic", a.Name(), a.Model, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var parsedResp anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsedResp); err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	for _, block := range parsedResp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("anthropic returned no text content")
+	}
+
+	return text.String(), nil
+}
+
+func (a *AnthropicProvider) ChatStream(ctx context.Context, messages []Message, onChunk func(string)) (string, error) {
+	req, err := a.newRequest(ctx, messages, true)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := a.http.do(req, "anthropic", a.Name(), a.Model, true)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	err = readSSE(resp.Body, func(data string) error {
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return err
+		}
+		switch event.Type {
+		case "content_block_delta":
+			if event.Delta.Type != "text_delta" || event.Delta.Text == "" {
+				return nil
+			}
+			full.WriteString(event.Delta.Text)
+			if onChunk != nil {
+				onChunk(event.Delta.Text)
+			}
+		case "error":
+			if event.Error != nil {
+				return fmt.Errorf("anthropic stream error: %s (%s)", event.Error.Message, event.Error.Type)
+			}
+			return fmt.Errorf("anthropic stream error")
+		}
+		return nil
+	})
+	if err != nil {
+		return full.String(), err
+	}
+
+	return full.String(), nil
+}
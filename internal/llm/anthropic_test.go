@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestAnthropic(t *testing.T, handler http.HandlerFunc) *AnthropicProvider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	p := NewAnthropicProvider("sk-ant-test", "claude-test", 256)
+	p.BaseURL = server.URL
+	return p
+}
+
+func TestAnthropicChat(t *testing.T) {
+	p := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %s, want /v1/messages", r.URL.Path)
+		}
+		if got := r.Header.Get("x-api-key"); got != "sk-ant-test" {
+			t.Errorf("x-api-key = %q", got)
+		}
+		if got := r.Header.Get("anthropic-version"); got != anthropicVersion {
+			t.Errorf("anthropic-version = %q", got)
+		}
+
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.System != "be brief" {
+			t.Errorf("system = %q, want top-level system prompt", req.System)
+		}
+		if req.MaxTokens != 256 {
+			t.Errorf("max_tokens = %d, want 256", req.MaxTokens)
+		}
+		for _, m := range req.Messages {
+			if m.Role == RoleSystem {
+				t.Errorf("system message leaked into messages: %+v", m)
+			}
+		}
+
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"ls "},{"type":"text","text":"-la"}],"stop_reason":"end_turn"}`)
+	})
+
+	got, err := p.Query(context.Background(), "be brief", "list files")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if got != "ls -la" {
+		t.Errorf("Query() = %q, want %q", got, "ls -la")
+	}
+}
+
+func TestAnthropicErrorBody(t *testing.T) {
+	p := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"not_found_error","message":"model: claude-nope"}}`)
+	})
+
+	_, err := p.Query(context.Background(), "sys", "q")
+	if err == nil {
+		t.Fatal("Query() expected error")
+	}
+	if !strings.Contains(err.Error(), "model: claude-nope") || !strings.Contains(err.Error(), "not_found_error") {
+		t.Errorf("error %q does not carry the API message", err)
+	}
+}
+
+func TestAnthropicChatStream(t *testing.T) {
+	p := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+		events := []string{
+			`event: message_start` + "\n" + `data: {"type":"message_start","message":{}}`,
+			`event: content_block_delta` + "\n" + `data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"git "}}`,
+			`event: ping` + "\n" + `data: {"type":"ping"}`,
+			`event: content_block_delta` + "\n" + `data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"status"}}`,
+			`event: message_stop` + "\n" + `data: {"type":"message_stop"}`,
+		}
+		for _, e := range events {
+			fmt.Fprintf(w, "%s\n\n", e)
+		}
+	})
+
+	var chunks []string
+	got, err := p.ChatStream(context.Background(), singleTurn("sys", "q"), func(c string) {
+		chunks = append(chunks, c)
+	})
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	if got != "git status" || len(chunks) != 2 {
+		t.Errorf("ChatStream() = %q in %d chunks, want %q in 2", got, len(chunks), "git status")
+	}
+}
+
+func TestAnthropicStreamErrorEvent(t *testing.T) {
+	p := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	})
+
+	_, err := p.ChatStream(context.Background(), singleTurn("sys", "q"), nil)
+	if err == nil || !strings.Contains(err.Error(), "Overloaded") {
+		t.Errorf("ChatStream() error = %v, want overloaded error", err)
+	}
+}
@@ -0,0 +1,289 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"huh/internal/redact"
+)
+
+// ResponseCache keeps answers on disk, one JSON file per request, so that
+// asking the same thing twice doesn't cost a second request.
+type ResponseCache struct {
+	Dir      string
+	TTL      time.Duration
+	MaxBytes int64 // The oldest entries are removed beyond this size
+
+	now func() time.Time
+}
+
+func NewResponseCache(dir string, ttl time.Duration, maxBytes int64) *ResponseCache {
+	return &ResponseCache{
+		Dir:      dir,
+		TTL:      ttl,
+		MaxBytes: maxBytes,
+		now:      time.Now,
+	}
+}
+
+type cacheEntry struct {
+	Created  time.Time `json:"created"`
+	Provider string    `json:"provider"`
+	Model    string    `json:"model"`
+	Answer   string    `json:"answer"`
+}
+
+// cacheKey hashes everything that decides the answer. Each part is length
+// prefixed so that moving text between parts changes the key.
+func cacheKey(provider, model string, messages []Message) string {
+	h := sha256.New()
+	write := func(s string) { fmt.Fprintf(h, "%d:%s", len(s), s) }
+	write(provider)
+	write(model)
+	for _, m := range messages {
+		write(m.Role)
+		write(m.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *ResponseCache) path(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+// Get returns the cached answer for key if there is one that hasn't expired.
+func (c *ResponseCache) Get(key string) (string, bool) {
+	b, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return "", false
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		os.Remove(c.path(key))
+		return "", false
+	}
+	if c.TTL > 0 && c.now().Sub(e.Created) > c.TTL {
+		os.Remove(c.path(key))
+		return "", false
+	}
+	return e.Answer, true
+}
+
+// Put stores an answer under key and trims the cache to its limits.
+func (c *ResponseCache) Put(key, provider, model, answer string) error {
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(cacheEntry{
+		Created:  c.now(),
+		Provider: provider,
+		Model:    model,
+		Answer:   answer,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file first so a concurrent Get never sees half an entry
+	tmp, err := os.CreateTemp(c.Dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return c.prune()
+}
+
+// prune removes expired entries, then the oldest ones until the cache fits
+// in MaxBytes.
+func (c *ResponseCache) prune() error {
+	dirEntries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return err
+	}
+
+	type file struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []file
+	var total int64
+	for _, d := range dirEntries {
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".json") {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		f := file{filepath.Join(c.Dir, d.Name()), info.Size(), info.ModTime()}
+		if c.TTL > 0 && c.now().Sub(f.modTime) > c.TTL {
+			os.Remove(f.path)
+			continue
+		}
+		files = append(files, f)
+		total += f.size
+	}
+
+	if c.MaxBytes <= 0 || total <= c.MaxBytes {
+		return nil
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files {
+		if total <= c.MaxBytes {
+			break
+		}
+		if err := os.Remove(f.path); err == nil {
+			total -= f.size
+		}
+	}
+	return nil
+}
+
+type refreshCacheKey struct{}
+
+// RefreshCache returns a context for which cached providers skip the
+// lookup and store the fresh answer instead, e.g. to regenerate an answer.
+func RefreshCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshCacheKey{}, true)
+}
+
+func refreshingCache(ctx context.Context) bool {
+	v, _ := ctx.Value(refreshCacheKey{}).(bool)
+	return v
+}
+
+// FromCache reports whether the last answer of p came from the response
+// cache.
+func FromCache(p LLM) bool {
+	c, ok := p.(interface{ FromCache() bool })
+	return ok && c.FromCache()
+}
+
+// CachedProvider answers from a ResponseCache when it can and stores every
+// new answer of the wrapped provider in it.
+type CachedProvider struct {
+	name  string // Configured name of the provider, part of the key
+	inner LLM
+	cache *ResponseCache
+
+	mu  sync.Mutex
+	hit bool // The last answer came from the cache
+}
+
+func NewCachedProvider(name string, p LLM, cache *ResponseCache) *CachedProvider {
+	return &CachedProvider{
+		name:  name,
+		inner: p,
+		cache: cache,
+	}
+}
+
+func (c *CachedProvider) Name() string {
+	return c.inner.Name()
+}
+
+func (c *CachedProvider) ModelName() string {
+	return c.inner.ModelName()
+}
+
+// FromCache reports whether the last answer came from the cache.
+func (c *CachedProvider) FromCache() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hit
+}
+
+func (c *CachedProvider) ContextWindow() int {
+	return ContextWindow(c.inner)
+}
+
+// Models always asks the provider, model lists aren't cached.
+func (c *CachedProvider) Models(ctx context.Context) ([]string, error) {
+	return Models(ctx, c.inner)
+}
+
+// Redacted returns what the wrapped provider masked in the last request.
+// Nothing is sent for cached answers.
+func (c *CachedProvider) Redacted() []redact.Finding {
+	if c.FromCache() {
+		return nil
+	}
+	return Redacted(c.inner)
+}
+
+func (c *CachedProvider) PreviewRedaction(text string) []redact.Finding {
+	return PreviewRedaction(c.inner, text)
+}
+
+func (c *CachedProvider) Query(ctx context.Context, systemPrompt string, userQuery string) (string, error) {
+	return c.Chat(ctx, singleTurn(systemPrompt, userQuery))
+}
+
+func (c *CachedProvider) Stream(ctx context.Context, systemPrompt string, userQuery string, onChunk func(string)) (string, error) {
+	return c.ChatStream(ctx, singleTurn(systemPrompt, userQuery), onChunk)
+}
+
+func (c *CachedProvider) Chat(ctx context.Context, messages []Message) (string, error) {
+	return c.lookup(ctx, messages, nil, func() (string, error) {
+		return c.inner.Chat(ctx, messages)
+	})
+}
+
+func (c *CachedProvider) ChatStream(ctx context.Context, messages []Message, onChunk func(string)) (string, error) {
+	return c.lookup(ctx, messages, onChunk, func() (string, error) {
+		return c.inner.ChatStream(ctx, messages, onChunk)
+	})
+}
+
+// lookup answers from the cache, or asks the provider through fetch and
+// caches the result. A cached answer is streamed as a single chunk.
+func (c *CachedProvider) lookup(ctx context.Context, messages []Message, onChunk func(string), fetch func() (string, error)) (string, error) {
+	key := cacheKey(c.name+"/"+c.inner.Name(), c.inner.ModelName(), messages)
+
+	if !refreshingCache(ctx) {
+		if answer, ok := c.cache.Get(key); ok {
+			c.setHit(true)
+			if onChunk != nil {
+				onChunk(answer)
+			}
+			return answer, nil
+		}
+	}
+
+	answer, err := fetch()
+	c.setHit(false)
+	if err != nil {
+		return answer, err
+	}
+	// A failing cache shouldn't fail the request
+	_ = c.cache.Put(key, c.inner.Name(), c.inner.ModelName(), answer)
+	return answer, nil
+}
+
+func (c *CachedProvider) setHit(hit bool) {
+	c.mu.Lock()
+	c.hit = hit
+	c.mu.Unlock()
+}
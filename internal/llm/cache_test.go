@@ -0,0 +1,145 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"huh/internal/config"
+)
+
+func TestCacheKey(t *testing.T) {
+	base := cacheKey("ollama", "llama3", singleTurn("sys", "q"))
+	for name, key := range map[string]string{
+		"provider": cacheKey("openai", "llama3", singleTurn("sys", "q")),
+		"model":    cacheKey("ollama", "llama3:70b", singleTurn("sys", "q")),
+		"system":   cacheKey("ollama", "llama3", singleTurn("sys2", "q")),
+		"user":     cacheKey("ollama", "llama3", singleTurn("sys", "q2")),
+		"boundary": cacheKey("ollama", "llama3", singleTurn("sysq", "")),
+	} {
+		if key == base {
+			t.Errorf("changing the %s doesn't change the key", name)
+		}
+	}
+	if cacheKey("ollama", "llama3", singleTurn("sys", "q")) != base {
+		t.Error("key isn't stable")
+	}
+}
+
+func TestResponseCacheTTL(t *testing.T) {
+	now := time.Now()
+	c := NewResponseCache(t.TempDir(), time.Hour, 0)
+	c.now = func() time.Time { return now }
+
+	if err := c.Put("k", "ollama", "llama3", "answer"); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := c.Get("k"); !ok || got != "answer" {
+		t.Fatalf("Get() = %q, %v", got, ok)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := c.Get("k"); ok {
+		t.Error("Get() returned an expired entry")
+	}
+	if _, err := os.Stat(c.path("k")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("expired entry wasn't removed")
+	}
+}
+
+func TestResponseCacheMaxBytes(t *testing.T) {
+	dir := t.TempDir()
+	c := NewResponseCache(dir, 0, 300)
+
+	old := time.Now().Add(-time.Minute)
+	for i, key := range []string{"a", "b", "c"} {
+		if err := c.Put(key, "p", "m", strings.Repeat("x", 100)); err != nil {
+			t.Fatal(err)
+		}
+		// Give every entry a distinct age, oldest first
+		mod := old.Add(time.Duration(i) * time.Second)
+		os.Chtimes(filepath.Join(dir, key+".json"), mod, mod)
+	}
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("oldest entry should have been pruned")
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Error("newest entry should have been kept")
+	}
+}
+
+func TestCachedProvider(t *testing.T) {
+	stub := &stubLLM{name: "ollama", reply: "ls -la"}
+	p := NewCachedProvider("local", stub, NewResponseCache(t.TempDir(), time.Hour, 0))
+	ctx := context.Background()
+
+	var chunks []string
+	onChunk := func(c string) { chunks = append(chunks, c) }
+
+	if _, err := p.Stream(ctx, "sys", "q", onChunk); err != nil {
+		t.Fatal(err)
+	}
+	if p.FromCache() || stub.calls != 1 {
+		t.Fatalf("first request: FromCache() = %v, calls = %d", p.FromCache(), stub.calls)
+	}
+
+	chunks = nil
+	got, err := p.Stream(ctx, "sys", "q", onChunk)
+	if err != nil || got != "ls -la" {
+		t.Fatalf("Stream() = %q, %v", got, err)
+	}
+	if !p.FromCache() || stub.calls != 1 {
+		t.Errorf("repeat request: FromCache() = %v, calls = %d", p.FromCache(), stub.calls)
+	}
+	if len(chunks) != 1 || chunks[0] != "ls -la" {
+		t.Errorf("cached answer streamed as %q", chunks)
+	}
+
+	if _, err := p.Stream(RefreshCache(ctx), "sys", "q", nil); err != nil {
+		t.Fatal(err)
+	}
+	if p.FromCache() || stub.calls != 2 {
+		t.Errorf("refresh: FromCache() = %v, calls = %d", p.FromCache(), stub.calls)
+	}
+
+	// Failures aren't cached
+	stub.err = errors.New("boom")
+	if _, err := p.Query(ctx, "sys", "other"); err == nil {
+		t.Error("expected error")
+	}
+	stub.err = nil
+	if _, err := p.Query(ctx, "sys", "other"); err != nil || p.FromCache() {
+		t.Errorf("failed answer was cached: %v", err)
+	}
+}
+
+func TestNewProviderWithCache(t *testing.T) {
+	saved := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = saved })
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	config.AppConfig.Providers = map[string]config.ProviderConfig{
+		"local": {Type: "ollama"},
+	}
+	config.AppConfig.DefaultProvider = "local"
+	config.AppConfig.Fallback = nil
+
+	config.AppConfig.Cache = config.CacheConfig{Enabled: true}
+	p, err := NewProvider("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(*CachedProvider); !ok {
+		t.Errorf("NewProvider() = %T, want *CachedProvider", p)
+	}
+
+	config.AppConfig.Cache = config.CacheConfig{Enabled: true, TTL: "soon"}
+	if _, err := NewProvider(""); err == nil {
+		t.Error("expected error for invalid ttl")
+	}
+}
@@ -0,0 +1,29 @@
+package llm
+
+import "context"
+
+// Conversation accumulates the messages exchanged with a provider so that
+// follow-up requests carry the full history.
+type Conversation struct {
+	Messages []Message
+}
+
+// Reset starts a new conversation with the given system prompt.
+func (c *Conversation) Reset(systemPrompt string) {
+	c.Messages = []Message{{Role: RoleSystem, Content: systemPrompt}}
+}
+
+// Send appends a user message, streams the reply from p and records it.
+// The history is left untouched if the provider fails, so the request can
+// simply be sent again.
+func (c *Conversation) Send(ctx context.Context, p LLM, userMessage string, onChunk func(string)) (string, error) {
+	messages := append(append([]Message(nil), c.Messages...), Message{Role: RoleUser, Content: userMessage})
+
+	res, err := p.ChatStream(ctx, messages, onChunk)
+	if err != nil {
+		return "", err
+	}
+
+	c.Messages = append(messages, Message{Role: RoleAssistant, Content: res})
+	return res, nil
+}
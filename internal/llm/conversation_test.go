@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// echoLLM replies with the number of messages it received.
+type echoLLM struct {
+	fail bool
+	seen [][]Message
+}
+
+func (e *echoLLM) Name() string { return "echo" }
+
+func (e *echoLLM) ModelName() string { return "echo" }
+
+func (e *echoLLM) Query(ctx context.Context, systemPrompt, userQuery string) (string, error) {
+	return e.Chat(ctx, singleTurn(systemPrompt, userQuery))
+}
+
+func (e *echoLLM) Stream(ctx context.Context, systemPrompt, userQuery string, onChunk func(string)) (string, error) {
+	return e.ChatStream(ctx, singleTurn(systemPrompt, userQuery), onChunk)
+}
+
+func (e *echoLLM) Chat(ctx context.Context, messages []Message) (string, error) {
+	return e.ChatStream(ctx, messages, nil)
+}
+
+func (e *echoLLM) ChatStream(ctx context.Context, messages []Message, onChunk func(string)) (string, error) {
+	e.seen = append(e.seen, messages)
+	if e.fail {
+		return "", errors.New("boom")
+	}
+	return messages[len(messages)-1].Content + "!", nil
+}
+
+func TestConversationSend(t *testing.T) {
+	p := &echoLLM{}
+	conv := &Conversation{}
+	conv.Reset("sys")
+
+	if _, err := conv.Send(context.Background(), p, "first", nil); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if _, err := conv.Send(context.Background(), p, "second", nil); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	// system, user, assistant, user
+	if got := len(p.seen[1]); got != 4 {
+		t.Errorf("second request carried %d messages, want 4", got)
+	}
+	if got := conv.Messages[2]; got.Role != RoleAssistant || got.Content != "first!" {
+		t.Errorf("history[2] = %+v, want assistant reply to first", got)
+	}
+
+	p.fail = true
+	if _, err := conv.Send(context.Background(), p, "third", nil); err == nil {
+		t.Fatal("Send() expected error")
+	}
+	if len(conv.Messages) != 5 {
+		t.Errorf("failed Send changed history to %d messages, want 5", len(conv.Messages))
+	}
+}
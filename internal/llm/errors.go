@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Kinds of provider failure. Errors returned by providers wrap one of these
+// when the failure could be classified, test with errors.Is.
+var (
+	ErrAuth           = errors.New("authentication failed")
+	ErrModelNotFound  = errors.New("model not found")
+	ErrRateLimited    = errors.New("rate limited")
+	ErrContextTooLong = errors.New("context too long")
+	ErrNetwork        = errors.New("network unreachable")
+	ErrTimeout        = errors.New("request timed out")
+)
+
+// APIError is returned when a provider answers with a non-200 status.
+type APIError struct {
+	Provider   string // Configured name
+	Type       string // Provider type, like ollama
+	Model      string
+	Endpoint   string // Scheme and host the request was sent to
+	StatusCode int
+	Message    string        // Error message from the response body, if any
+	RetryAfter time.Duration // From the Retry-After header, if any
+	Kind       error         // One of the Err* kinds, nil if unclassified
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s API error: status %d: %s", e.Provider, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s API error: status %d", e.Provider, e.StatusCode)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+// RequestError is returned when a provider could not be reached at all.
+type RequestError struct {
+	Provider string // Configured name
+	Type     string // Provider type, like ollama
+	Endpoint string
+	Kind     error // ErrNetwork or ErrTimeout
+	Err      error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("%s: %v: %v", e.Provider, e.Kind, e.Err)
+}
+
+func (e *RequestError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// classifyStatus maps an HTTP error response to one of the Err* kinds.
+// Providers disagree on status codes, so the message is taken into account.
+func classifyStatus(status int, message string) error {
+	msg := strings.ToLower(message)
+	mentions := func(words ...string) bool {
+		for _, w := range words {
+			if strings.Contains(msg, w) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrAuth
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status == http.StatusRequestEntityTooLarge,
+		mentions("context length", "context_length", "context window", "maximum context", "prompt is too long", "too many tokens"):
+		return ErrContextTooLong
+	case (status == http.StatusNotFound || status == http.StatusBadRequest) &&
+		mentions("model") && mentions("not found", "does not exist", "not a valid model", "invalid model", "no endpoints"):
+		return ErrModelNotFound
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return ErrTimeout
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyStatus(t *testing.T) {
+	tests := []struct {
+		status  int
+		message string
+		want    error
+	}{
+		{401, "Incorrect API key provided", ErrAuth},
+		{403, "", ErrAuth},
+		{429, "Rate limit reached", ErrRateLimited},
+		{404, `model "llama9" not found, try pulling it first`, ErrModelNotFound},
+		{404, "The model `gpt-9` does not exist or you do not have access to it.", ErrModelNotFound},
+		{400, "foo/bar is not a valid model ID", ErrModelNotFound},
+		{400, "This model's maximum context length is 8192 tokens.", ErrContextTooLong},
+		{400, "prompt is too long: 210000 tokens > 200000 maximum", ErrContextTooLong},
+		{413, "", ErrContextTooLong},
+		{504, "", ErrTimeout},
+		{500, "internal error", nil},
+		{404, "page not found", nil},
+	}
+	for _, tt := range tests {
+		if got := classifyStatus(tt.status, tt.message); got != tt.want {
+			t.Errorf("classifyStatus(%d, %q) = %v, want %v", tt.status, tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestProviderErrorsAreTyped(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"model \"llama9\" not found, try pulling it first"}`))
+	}))
+	defer server.Close()
+
+	p := NewOllamaProvider(server.URL, "llama9")
+	_, err := p.Query(t.Context(), "sys", "q")
+
+	if !errors.Is(err, ErrModelNotFound) {
+		t.Fatalf("error %v is not ErrModelNotFound", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Model != "llama9" || apiErr.Endpoint != server.URL {
+		t.Errorf("APIError = %+v, want model and endpoint recorded", apiErr)
+	}
+}
+
+func TestUnreachableProviderIsNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	p := NewOllamaProvider(url, "m")
+	p.http = newTransport(time.Second, 0)
+	_, err := p.Query(t.Context(), "sys", "q")
+
+	if !errors.Is(err, ErrNetwork) {
+		t.Fatalf("error %v is not ErrNetwork", err)
+	}
+	if !strings.Contains(err.Error(), "ollama") {
+		t.Errorf("error %q does not name the provider", err)
+	}
+}
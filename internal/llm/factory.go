@@ -2,55 +2,250 @@ package llm
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
 	"huh/internal/config"
+	"huh/internal/redact"
 )
 
+// NewProvider creates the named provider. If name is empty the default
+// provider is used, chained with the configured fallback providers.
 func NewProvider(name string) (LLM, error) {
+	if name != "" {
+		return newProvider(name)
+	}
+
 	// If name is empty, use default from config
-	if name == "" {
-		name = config.AppConfig.DefaultProvider
+	names := []string{config.AppConfig.DefaultProvider}
+	for _, fb := range config.AppConfig.Fallback {
+		if !slices.Contains(names, fb) {
+			names = append(names, fb)
+		}
+	}
+	if len(names) == 1 {
+		return newProvider(names[0])
+	}
+
+	// The fallbacks resolve their secrets only once they're needed
+	primary, err := newProvider(names[0])
+	if err != nil {
+		return nil, err
+	}
+	providers := []LLM{primary}
+	for _, n := range names[1:] {
+		p, err := newLazyProvider(n)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	return NewFallbackProvider(names, providers), nil
+}
+
+// Response cache defaults, used when the config leaves them out
+const (
+	defaultCacheTTL       = 24 * time.Hour
+	defaultCacheMaxSizeMB = 20
+)
+
+// newProvider creates the named provider, wrapped in secret redaction and
+// the response cache if those are enabled.
+func newProvider(name string) (LLM, error) {
+	return wrapProvider(name, buildProvider)
+}
+
+func wrapProvider(name string, build func(string) (LLM, error)) (LLM, error) {
+	p, err := build(name)
+	if err != nil {
+		return nil, err
+	}
+	if cfg := config.AppConfig.Redact; cfg.Enabled && (cfg.Local || !isLocal(config.AppConfig.Providers[name])) {
+		redactor, err := redact.New(cfg.Patterns)
+		if err != nil {
+			return nil, err
+		}
+		p = NewRedactingProvider(p, redactor)
+	}
+	if !config.AppConfig.Cache.Enabled {
+		return p, nil
+	}
+	cache, err := responseCacheFromConfig(config.AppConfig.Cache)
+	if err != nil {
+		return nil, err
+	}
+	return NewCachedProvider(name, p, cache), nil
+}
+
+func responseCacheFromConfig(cfg config.CacheConfig) (*ResponseCache, error) {
+	ttl := defaultCacheTTL
+	if cfg.TTL != "" {
+		d, err := time.ParseDuration(cfg.TTL)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("cache: invalid ttl %q", cfg.TTL)
+		}
+		ttl = d
+	}
+	maxSizeMB := cfg.MaxSizeMB
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultCacheMaxSizeMB
 	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("cache: %w", err)
+	}
+	return NewResponseCache(filepath.Join(cacheDir, "huh", "responses"), ttl, int64(maxSizeMB)<<20), nil
+}
 
+func buildProvider(name string) (LLM, error) {
 	providerConfig, ok := config.AppConfig.Providers[name]
 	if !ok {
 		return nil, fmt.Errorf("provider '%s' not found in configuration", name)
 	}
+	params, err := config.ResolveParams(providerConfig.Params)
+	if err != nil {
+		return nil, fmt.Errorf("provider '%s': %w", name, err)
+	}
+	return buildProviderWith(name, providerConfig, params)
+}
+
+// buildUnresolved creates the named provider without running api_key_cmd
+// or keyring lookups, and leaving unset ${VAR} references as they are. It
+// can't send requests, but knows its model and context window.
+func buildUnresolved(name string) (LLM, error) {
+	providerConfig, ok := config.AppConfig.Providers[name]
+	if !ok {
+		return nil, fmt.Errorf("provider '%s' not found in configuration", name)
+	}
+	params := map[string]string{}
+	for k, v := range providerConfig.Params {
+		if expanded, err := config.ExpandEnv(v); err == nil {
+			v = expanded
+		}
+		params[k] = v
+	}
+	if params["api_key"] == "" {
+		params["api_key"] = config.KeySource(params)
+	}
+	return buildProviderWith(name, providerConfig, params)
+}
+
+func buildProviderWith(name string, providerConfig config.ProviderConfig, params map[string]string) (LLM, error) {
+	transport, err := transportFromParams(params)
+	if err != nil {
+		return nil, fmt.Errorf("provider '%s': %w", name, err)
+	}
+	window, err := contextWindowParam(params)
+	if err != nil {
+		return nil, fmt.Errorf("provider '%s': %w", name, err)
+	}
 
 	switch providerConfig.Type {
 	case "ollama":
-		host := providerConfig.Params["host"]
-		model := providerConfig.Params["model"]
+		host := params["host"]
+		model := params["model"]
 		if host == "" {
 			host = "http://localhost:11434"
 		}
 		if model == "" {
 			model = "llama3:8b"
 		}
-		return NewOllamaProvider(host, model), nil
+		p := NewOllamaProvider(host, model)
+		p.Window = window
+		p.ProviderName = name
+		p.http = transport
+		return p, nil
 
 	case "openai":
-		apiKey := providerConfig.Params["api_key"]
-		model := providerConfig.Params["model"]
+		apiKey := params["api_key"]
+		model := params["model"]
 		if apiKey == "" {
 			return nil, fmt.Errorf("openai provider '%s' missing api_key", name)
 		}
 		if model == "" {
 			model = "gpt-4-turbo"
 		}
-		return NewOpenAIProvider(apiKey, model), nil
+		p := NewOpenAIProvider(apiKey, model)
+		applyOpenAICompatibleParams(p.OpenAICompatibleProvider, params)
+		p.Window = window
+		p.ProviderName = name
+		p.http = transport
+		return p, nil
 
 	case "openrouter":
-		apiKey := providerConfig.Params["api_key"]
-		model := providerConfig.Params["model"]
+		apiKey := params["api_key"]
+		model := params["model"]
 		if apiKey == "" {
 			return nil, fmt.Errorf("openrouter provider '%s' missing api_key", name)
 		}
 		if model == "" {
 			model = "openai/gpt-3.5-turbo" // Default model for openrouter, just an example
 		}
-		return NewOpenRouterProvider(apiKey, model), nil
+		p := NewOpenRouterProvider(apiKey, model)
+		applyOpenAICompatibleParams(p.OpenAICompatibleProvider, params)
+		p.Window = window
+		p.ProviderName = name
+		p.http = transport
+		return p, nil
+
+	case "openai_compatible":
+		baseURL := params["base_url"]
+		if baseURL == "" {
+			return nil, fmt.Errorf("openai_compatible provider '%s' missing base_url", name)
+		}
+		p := NewOpenAICompatibleProvider(baseURL, params["api_key"], params["model"])
+		applyOpenAICompatibleParams(p, params)
+		p.Window = window
+		p.ProviderName = name
+		p.http = transport
+		return p, nil
+
+	case "anthropic":
+		apiKey := params["api_key"]
+		model := params["model"]
+		if apiKey == "" {
+			return nil, fmt.Errorf("anthropic provider '%s' missing api_key", name)
+		}
+		if model == "" {
+			model = "claude-3-5-sonnet-latest"
+		}
+		maxTokens := 1024
+		if v := params["max_tokens"]; v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("anthropic provider '%s' has invalid max_tokens %q", name, v)
+			}
+			maxTokens = n
+		}
+		p := NewAnthropicProvider(apiKey, model, maxTokens)
+		if v := params["base_url"]; v != "" {
+			p.BaseURL = strings.TrimRight(v, "/")
+		}
+		p.Window = window
+		p.ProviderName = name
+		p.http = transport
+		return p, nil
 
 	default:
 		return nil, fmt.Errorf("unsupported provider type: %s", providerConfig.Type)
 	}
 }
+
+// applyOpenAICompatibleParams applies the optional params shared by every
+// OpenAI-compatible provider type.
+func applyOpenAICompatibleParams(p *OpenAICompatibleProvider, params map[string]string) {
+	if v := params["base_url"]; v != "" {
+		p.BaseURL = v
+	}
+	p.Organization = params["organization"]
+	p.Project = params["project"]
+	for k, v := range params {
+		if name, ok := strings.CutPrefix(k, config.HeaderParamPrefix); ok && name != "" {
+			p.Headers[name] = v
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package llm
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"huh/internal/config"
@@ -23,6 +25,22 @@ func TestNewProvider(t *testing.T) {
 				"model":   "gpt-test",
 			},
 		},
+		"anthropic": {
+			Type: "anthropic",
+			Params: map[string]string{
+				"api_key":    "sk-ant-test",
+				"model":      "claude-test",
+				"max_tokens": "2048",
+				"base_url":   "http://proxy.test:8080/",
+			},
+		},
+		"anthropic-bad": {
+			Type: "anthropic",
+			Params: map[string]string{
+				"api_key":    "sk-ant-test",
+				"max_tokens": "lots",
+			},
+		},
 	}
 	config.AppConfig.DefaultProvider = "ollama"
 
@@ -54,6 +72,18 @@ func TestNewProvider(t *testing.T) {
 			wantType:      "openai",
 			expectedModel: "gpt-test", // Check model to confirm params passed
 		},
+		{
+			name:          "Explicit Anthropic",
+			providerName:  "anthropic",
+			wantErr:       false,
+			wantType:      "anthropic",
+			expectedModel: "claude-test",
+		},
+		{
+			name:         "Anthropic Invalid max_tokens",
+			providerName: "anthropic-bad",
+			wantErr:      true,
+		},
 		{
 			name:         "Unknown Provider",
 			providerName: "missing",
@@ -83,7 +113,52 @@ func TestNewProvider(t *testing.T) {
 						t.Errorf("OpenAIProvider.Model = %v, want %v", o.Model, tt.expectedModel)
 					}
 				}
+				if a, ok := got.(*AnthropicProvider); ok {
+					if a.Model != tt.expectedModel {
+						t.Errorf("AnthropicProvider.Model = %v, want %v", a.Model, tt.expectedModel)
+					}
+					if a.MaxTokens != 2048 {
+						t.Errorf("AnthropicProvider.MaxTokens = %v, want 2048", a.MaxTokens)
+					}
+					if a.BaseURL != "http://proxy.test:8080" {
+						t.Errorf("AnthropicProvider.BaseURL = %v, want http://proxy.test:8080", a.BaseURL)
+					}
+				}
 			}
 		})
 	}
 }
+
+func TestProviderName(t *testing.T) {
+	saved := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = saved })
+
+	config.AppConfig.Providers = map[string]config.ProviderConfig{
+		"gpu-box": {Type: "ollama", Params: map[string]string{"host": "http://127.0.0.1:1", "max_retries": "0"}},
+		"claude":  {Type: "anthropic", Params: map[string]string{"api_key": "sk-ant-test"}},
+		"work":    {Type: "openai", Params: map[string]string{"api_key": "sk-test"}},
+	}
+	config.AppConfig.Cache.Enabled = false
+	config.AppConfig.Redact.Enabled = false
+
+	for name := range config.AppConfig.Providers {
+		p, err := NewProvider(name)
+		if err != nil {
+			t.Fatalf("NewProvider(%q) error = %v", name, err)
+		}
+		if p.Name() != name {
+			t.Errorf("NewProvider(%q).Name() = %q", name, p.Name())
+		}
+	}
+
+	// Errors name the provider as configured, and say what type it is
+	p, _ := NewProvider("gpu-box")
+	_, err := p.Query(context.Background(), "sys", "hi")
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("Query() error = %v, want a RequestError", err)
+	}
+	if reqErr.Provider != "gpu-box" || reqErr.Type != "ollama" {
+		t.Errorf("RequestError Provider = %q, Type = %q, want gpu-box, ollama", reqErr.Provider, reqErr.Type)
+	}
+}
@@ -0,0 +1,160 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"huh/internal/redact"
+)
+
+// FallbackProvider tries an ordered list of providers and moves on to the
+// next one when a provider is unreachable, times out or fails with a 5xx.
+// Errors the user has to fix (bad key, unknown model, ...) are returned
+// straight away.
+type FallbackProvider struct {
+	names     []string
+	providers []LLM
+
+	mu   sync.Mutex
+	last int // Index of the provider that answered last
+}
+
+func NewFallbackProvider(names []string, providers []LLM) *FallbackProvider {
+	return &FallbackProvider{
+		names:     names,
+		providers: providers,
+	}
+}
+
+// Name returns the configured name of the provider that answered the last
+// request, or of the primary provider before any request was made.
+func (f *FallbackProvider) Name() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.names[f.last]
+}
+
+// ModelName returns the model of the provider that answered last.
+func (f *FallbackProvider) ModelName() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.providers[f.last].ModelName()
+}
+
+// FromCache reports whether the provider that answered last did so from
+// the response cache.
+func (f *FallbackProvider) FromCache() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return FromCache(f.providers[f.last])
+}
+
+// ContextWindow is the smallest window in the chain, so that whichever
+// provider ends up answering gets everything.
+func (f *FallbackProvider) ContextWindow() int {
+	window := ContextWindow(f.providers[0])
+	for _, p := range f.providers[1:] {
+		window = min(window, ContextWindow(p))
+	}
+	return window
+}
+
+// Redacted returns what the provider that answered last masked.
+func (f *FallbackProvider) Redacted() []redact.Finding {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return Redacted(f.providers[f.last])
+}
+
+// PreviewRedaction returns what the first provider that redacts would
+// mask. Redaction is configured once, so they'd all mask the same.
+func (f *FallbackProvider) PreviewRedaction(text string) []redact.Finding {
+	for _, p := range f.providers {
+		if findings := PreviewRedaction(p, text); len(findings) > 0 {
+			return findings
+		}
+	}
+	return nil
+}
+
+func (f *FallbackProvider) Query(ctx context.Context, systemPrompt string, userQuery string) (string, error) {
+	return f.Chat(ctx, singleTurn(systemPrompt, userQuery))
+}
+
+func (f *FallbackProvider) Stream(ctx context.Context, systemPrompt string, userQuery string, onChunk func(string)) (string, error) {
+	return f.ChatStream(ctx, singleTurn(systemPrompt, userQuery), onChunk)
+}
+
+func (f *FallbackProvider) Chat(ctx context.Context, messages []Message) (string, error) {
+	return f.try(ctx, func(p LLM) (string, bool, error) {
+		res, err := p.Chat(ctx, messages)
+		return res, false, err
+	})
+}
+
+func (f *FallbackProvider) ChatStream(ctx context.Context, messages []Message, onChunk func(string)) (string, error) {
+	return f.try(ctx, func(p LLM) (string, bool, error) {
+		// Once part of an answer has been shown we can't switch providers
+		// without the output getting mixed up.
+		started := false
+		res, err := p.ChatStream(ctx, messages, func(chunk string) {
+			started = true
+			if onChunk != nil {
+				onChunk(chunk)
+			}
+		})
+		return res, started, err
+	})
+}
+
+// try runs fn against each provider in turn. fn reports whether it already
+// produced output, in which case falling back is no longer possible.
+func (f *FallbackProvider) try(ctx context.Context, fn func(LLM) (string, bool, error)) (string, error) {
+	var errs []string
+	for i, p := range f.providers {
+		res, started, err := fn(p)
+		if err == nil {
+			f.mu.Lock()
+			f.last = i
+			f.mu.Unlock()
+			return res, nil
+		}
+		if started || !shouldFallback(ctx, err) || i == len(f.providers)-1 {
+			if len(errs) == 0 {
+				return res, err
+			}
+			return res, fmt.Errorf("%w (after: %s)", err, strings.Join(errs, "; "))
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", f.names[i], err))
+	}
+	return "", errors.New("no providers configured")
+}
+
+// shouldFallback reports whether err means the provider is unavailable
+// rather than the request being wrong.
+func shouldFallback(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		// The caller gave up, don't keep trying on their behalf
+		return false
+	}
+
+	var setupErr *setupError
+	if errors.As(err, &setupErr) {
+		return true
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 || errors.Is(err, ErrTimeout)
+	}
+
+	if errors.Is(err, ErrNetwork) || errors.Is(err, ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
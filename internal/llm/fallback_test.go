@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"huh/internal/config"
+)
+
+// stubLLM answers with a fixed reply or error, optionally streaming a
+// chunk before failing.
+type stubLLM struct {
+	name        string
+	reply       string
+	err         error
+	chunkBefore bool
+	calls       int
+}
+
+func (s *stubLLM) Name() string { return s.name }
+
+func (s *stubLLM) ModelName() string { return "stub" }
+
+func (s *stubLLM) Query(ctx context.Context, systemPrompt, userQuery string) (string, error) {
+	return s.Chat(ctx, singleTurn(systemPrompt, userQuery))
+}
+
+func (s *stubLLM) Stream(ctx context.Context, systemPrompt, userQuery string, onChunk func(string)) (string, error) {
+	return s.ChatStream(ctx, singleTurn(systemPrompt, userQuery), onChunk)
+}
+
+func (s *stubLLM) Chat(ctx context.Context, messages []Message) (string, error) {
+	return s.ChatStream(ctx, messages, nil)
+}
+
+func (s *stubLLM) ChatStream(ctx context.Context, messages []Message, onChunk func(string)) (string, error) {
+	s.calls++
+	if s.chunkBefore && onChunk != nil {
+		onChunk("partial")
+	}
+	return s.reply, s.err
+}
+
+func TestFallbackProvider(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name        string
+		primary     *stubLLM
+		wantReply   string
+		wantErr     bool
+		wantName    string
+		wantBackups int
+	}{
+		{
+			name:        "primary answers",
+			primary:     &stubLLM{name: "ollama", reply: "a"},
+			wantReply:   "a",
+			wantName:    "local",
+			wantBackups: 0,
+		},
+		{
+			name:        "connection refused falls back",
+			primary:     &stubLLM{name: "ollama", err: dialErr},
+			wantReply:   "b",
+			wantName:    "cloud",
+			wantBackups: 1,
+		},
+		{
+			name:        "5xx falls back",
+			primary:     &stubLLM{name: "ollama", err: &APIError{Provider: "ollama", StatusCode: 503}},
+			wantReply:   "b",
+			wantName:    "cloud",
+			wantBackups: 1,
+		},
+		{
+			name:        "4xx is returned",
+			primary:     &stubLLM{name: "ollama", err: &APIError{Provider: "ollama", StatusCode: 404}},
+			wantErr:     true,
+			wantName:    "local",
+			wantBackups: 0,
+		},
+		{
+			name:        "no fallback after streaming started",
+			primary:     &stubLLM{name: "ollama", err: dialErr, chunkBefore: true},
+			wantErr:     true,
+			wantName:    "local",
+			wantBackups: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backup := &stubLLM{name: "openai", reply: "b"}
+			f := NewFallbackProvider([]string{"local", "cloud"}, []LLM{tt.primary, backup})
+
+			got, err := f.Stream(context.Background(), "sys", "q", func(string) {})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Stream() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.wantReply {
+				t.Errorf("Stream() = %q, want %q", got, tt.wantReply)
+			}
+			if f.Name() != tt.wantName {
+				t.Errorf("Name() = %q, want %q", f.Name(), tt.wantName)
+			}
+			if backup.calls != tt.wantBackups {
+				t.Errorf("backup called %d times, want %d", backup.calls, tt.wantBackups)
+			}
+		})
+	}
+}
+
+func TestNewProviderWithFallback(t *testing.T) {
+	saved := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = saved })
+
+	config.AppConfig.Providers = map[string]config.ProviderConfig{
+		"ollama": {Type: "ollama"},
+		"openai": {Type: "openai", Params: map[string]string{"api_key": "sk-test"}},
+	}
+	config.AppConfig.DefaultProvider = "ollama"
+	config.AppConfig.Fallback = []string{"openai", "ollama"}
+
+	p, err := NewProvider("")
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	f, ok := p.(*FallbackProvider)
+	if !ok {
+		t.Fatalf("NewProvider() = %T, want *FallbackProvider", p)
+	}
+	if len(f.providers) != 2 {
+		t.Errorf("chain has %d providers, want 2 (duplicates removed)", len(f.providers))
+	}
+
+	// An explicit name never builds a chain
+	if p, _ := NewProvider("openai"); p.Name() != "openai" {
+		t.Errorf("NewProvider(openai) Name = %q", p.Name())
+	}
+}
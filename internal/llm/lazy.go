@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"context"
+	"sync"
+
+	"huh/internal/redact"
+)
+
+// lazyProvider stands in for a fallback provider until it's first asked
+// something. An unused fallback never runs its api_key_cmd or keyring
+// lookup, which may prompt for a passphrase, and a ${VAR} it needs only
+// has to be set once it's used.
+type lazyProvider struct {
+	name       string
+	unresolved LLM // Built without secrets, for the model, window and redaction
+	build      func(name string) (LLM, error)
+
+	mu       sync.Mutex
+	built    bool
+	provider LLM
+	err      error
+}
+
+func newLazyProvider(name string) (*lazyProvider, error) {
+	unresolved, err := wrapProvider(name, buildUnresolved)
+	if err != nil {
+		return nil, err
+	}
+	return &lazyProvider{name: name, unresolved: unresolved, build: newProvider}, nil
+}
+
+// resolve builds the real provider the first time it's called.
+func (l *lazyProvider) resolve() (LLM, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.built {
+		l.provider, l.err = l.build(l.name)
+		if l.err != nil {
+			l.err = &setupError{err: l.err}
+		}
+		l.built = true
+	}
+	return l.provider, l.err
+}
+
+// current is the real provider once it's built, the unresolved one
+// until then.
+func (l *lazyProvider) current() LLM {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.built && l.err == nil {
+		return l.provider
+	}
+	return l.unresolved
+}
+
+func (l *lazyProvider) Name() string {
+	return l.current().Name()
+}
+
+func (l *lazyProvider) ModelName() string {
+	return l.current().ModelName()
+}
+
+func (l *lazyProvider) ContextWindow() int {
+	return ContextWindow(l.current())
+}
+
+func (l *lazyProvider) FromCache() bool {
+	return FromCache(l.current())
+}
+
+func (l *lazyProvider) Redacted() []redact.Finding {
+	return Redacted(l.current())
+}
+
+func (l *lazyProvider) PreviewRedaction(text string) []redact.Finding {
+	return PreviewRedaction(l.unresolved, text)
+}
+
+func (l *lazyProvider) Models(ctx context.Context) ([]string, error) {
+	p, err := l.resolve()
+	if err != nil {
+		return nil, err
+	}
+	return Models(ctx, p)
+}
+
+func (l *lazyProvider) Query(ctx context.Context, systemPrompt string, userQuery string) (string, error) {
+	return l.Chat(ctx, singleTurn(systemPrompt, userQuery))
+}
+
+func (l *lazyProvider) Stream(ctx context.Context, systemPrompt string, userQuery string, onChunk func(string)) (string, error) {
+	return l.ChatStream(ctx, singleTurn(systemPrompt, userQuery), onChunk)
+}
+
+func (l *lazyProvider) Chat(ctx context.Context, messages []Message) (string, error) {
+	p, err := l.resolve()
+	if err != nil {
+		return "", err
+	}
+	return p.Chat(ctx, messages)
+}
+
+func (l *lazyProvider) ChatStream(ctx context.Context, messages []Message, onChunk func(string)) (string, error) {
+	p, err := l.resolve()
+	if err != nil {
+		return "", err
+	}
+	return p.ChatStream(ctx, messages, onChunk)
+}
+
+// setupError is a fallback provider that couldn't be set up once it was
+// needed, e.g. because its API key couldn't be read. The next fallback is
+// tried, as if it were unreachable.
+type setupError struct {
+	err error
+}
+
+func (e *setupError) Error() string {
+	return e.err.Error()
+}
+
+func (e *setupError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"huh/internal/config"
+)
+
+func TestNewProviderResolvesFallbacksLazily(t *testing.T) {
+	saved := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = saved })
+
+	runs := filepath.Join(t.TempDir(), "runs")
+	config.AppConfig.Providers = map[string]config.ProviderConfig{
+		"ollama": {Type: "ollama"},
+		"openai": {Type: "openai", Params: map[string]string{
+			"api_key_cmd": "echo run >> " + runs + "; echo sk-test",
+			"model":       "gpt-4o",
+		}},
+		"anthropic": {Type: "anthropic", Params: map[string]string{"api_key": "${HUH_TEST_UNSET_KEY}"}},
+	}
+	config.AppConfig.DefaultProvider = "ollama"
+	config.AppConfig.Fallback = []string{"openai", "anthropic"}
+
+	p, err := NewProvider("")
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v, want unused fallbacks not to matter", err)
+	}
+	if _, err := os.Stat(runs); err == nil {
+		t.Error("api_key_cmd of a fallback ran before it was needed")
+	}
+
+	// The unresolved fallbacks still know their model and window
+	f := p.(*FallbackProvider)
+	if got := f.providers[1].ModelName(); got != "gpt-4o" {
+		t.Errorf("ModelName() = %q, want gpt-4o", got)
+	}
+	if got := ContextWindow(f.providers[2]); got != 200000 {
+		t.Errorf("ContextWindow() = %d, want the claude window", got)
+	}
+
+	// The primary provider is still set up right away
+	config.AppConfig.DefaultProvider = "anthropic"
+	config.AppConfig.Fallback = []string{"ollama"}
+	if _, err := NewProvider(""); err == nil {
+		t.Error("NewProvider() accepted a default provider without its key")
+	}
+}
+
+func TestLazyProvider(t *testing.T) {
+	builds := 0
+	backup := &stubLLM{name: "openai", reply: "b"}
+	l := &lazyProvider{
+		name:       "openai",
+		unresolved: &stubLLM{name: "openai"},
+		build: func(string) (LLM, error) {
+			builds++
+			return backup, nil
+		},
+	}
+	for range 2 {
+		if got, err := l.Chat(context.Background(), nil); err != nil || got != "b" {
+			t.Fatalf("Chat() = %q, %v", got, err)
+		}
+	}
+	if builds != 1 {
+		t.Errorf("built %d times, want once", builds)
+	}
+
+	// A fallback that can't be set up is skipped like an unreachable one
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	broken := &lazyProvider{
+		name:       "anthropic",
+		unresolved: &stubLLM{name: "anthropic"},
+		build: func(string) (LLM, error) {
+			return nil, errors.New("api_key: environment variable ANTHROPIC_API_KEY is not set")
+		},
+	}
+	last := &stubLLM{name: "openrouter", reply: "c"}
+	f := NewFallbackProvider([]string{"local", "anthropic", "openrouter"},
+		[]LLM{&stubLLM{name: "ollama", err: dialErr}, broken, last})
+	if got, err := f.Chat(context.Background(), nil); err != nil || got != "c" {
+		t.Errorf("Chat() = %q, %v, want the last fallback's answer", got, err)
+	}
+}
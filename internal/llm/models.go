@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ErrNoModelList is returned by Models for providers that can't list their
+// models.
+var ErrNoModelList = errors.New("provider can't list its models")
+
+// Models lists the models p can use, sorted.
+func Models(ctx context.Context, p LLM) ([]string, error) {
+	l, ok := p.(interface {
+		Models(context.Context) ([]string, error)
+	})
+	if !ok {
+		return nil, ErrNoModelList
+	}
+	models, err := l.Models(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(models)
+	return models, nil
+}
+
+// HasModel reports whether model is in models. Ollama lists "llama3" as
+// "llama3:latest", so an untagged name matches that too.
+func HasModel(models []string, model string) bool {
+	if slices.Contains(models, model) {
+		return true
+	}
+	return !strings.Contains(model, ":") && slices.Contains(models, model+":latest")
+}
+
+// listModels sends req and reads the OpenAI-style model list, which
+// Anthropic shares: {"data": [{"id": "..."}]}.
+func listModels(t *transport, req *http.Request, typ, provider string) ([]string, error) {
+	resp, err := t.do(req, typ, provider, "", false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var parsed struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+	models := make([]string, 0, len(parsed.Data))
+	for _, m := range parsed.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
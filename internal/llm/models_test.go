@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"huh/internal/config"
+)
+
+func TestModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			fmt.Fprint(w, `{"models":[{"name":"qwen2.5-coder:7b"},{"name":"llama3:latest"}]}`)
+		case "/v1/models":
+			if r.Header.Get("Authorization") != "Bearer sk-test" && r.Header.Get("x-api-key") != "sk-ant" {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"error":{"message":"bad key"}}`)
+				return
+			}
+			fmt.Fprint(w, `{"data":[{"id":"gpt-4o"},{"id":"gpt-4o-mini"}]}`)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	saved := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = saved })
+	config.AppConfig = config.Config{
+		Providers: map[string]config.ProviderConfig{
+			"ollama":    {Type: "ollama", Params: map[string]string{"host": server.URL}},
+			"openai":    {Type: "openai", Params: map[string]string{"api_key": "sk-test", "base_url": server.URL + "/v1"}},
+			"anthropic": {Type: "anthropic", Params: map[string]string{"api_key": "sk-ant"}},
+			"wrongkey":  {Type: "openai", Params: map[string]string{"api_key": "sk-nope", "base_url": server.URL + "/v1"}},
+		},
+		Redact: config.RedactConfig{Enabled: true},
+	}
+
+	tests := []struct {
+		name    string
+		want    []string
+		wantErr error
+	}{
+		{"ollama", []string{"llama3:latest", "qwen2.5-coder:7b"}, nil},
+		{"openai", []string{"gpt-4o", "gpt-4o-mini"}, nil},
+		{"anthropic", []string{"gpt-4o", "gpt-4o-mini"}, nil},
+		{"wrongkey", nil, ErrAuth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewProvider(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a, ok := p.(*RedactingProvider); ok {
+				if ap, ok := a.inner.(*AnthropicProvider); ok {
+					ap.BaseURL = server.URL
+				}
+			}
+			got, err := Models(context.Background(), p)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Models() error = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Models() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Models(context.Background(), &stubLLM{name: "stub"}); !errors.Is(err, ErrNoModelList) {
+		t.Errorf("Models(stub) error = %v, want ErrNoModelList", err)
+	}
+}
+
+func TestHasModel(t *testing.T) {
+	models := []string{"llama3:latest", "qwen2.5-coder:7b", "gpt-4o"}
+	tests := []struct {
+		model string
+		want  bool
+	}{
+		{"llama3", true},
+		{"llama3:latest", true},
+		{"llama3:70b", false},
+		{"qwen2.5-coder", false},
+		{"qwen2.5-coder:7b", true},
+		{"gpt-4o", true},
+		{"gpt-4", false},
+	}
+	for _, tt := range tests {
+		if got := HasModel(models, tt.model); got != tt.want {
+			t.Errorf("HasModel(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+}
@@ -5,74 +5,183 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"time"
-
+	"strings"
 )
 
 type OllamaProvider struct {
-	Host  string
-	Model string
+	ProviderName string // Configured name
+	Host         string
+	Model        string
+	Window       int // Sent as num_ctx, 0 keeps Ollama's default
+
+	http *transport
 }
 
 func NewOllamaProvider(host, model string) *OllamaProvider {
 	return &OllamaProvider{
-		Host:  host,
-		Model: model,
+		ProviderName: "ollama",
+		Host:         host,
+		Model:        model,
+		http:         defaultTransport(),
 	}
 }
 
 func (o *OllamaProvider) Name() string {
-	return "ollama"
+	return o.ProviderName
+}
+
+func (o *OllamaProvider) ModelName() string {
+	return o.Model
+}
+
+// ContextWindow is what Ollama is told to use, not what the model could
+// take: Ollama drops whatever doesn't fit in num_ctx.
+func (o *OllamaProvider) ContextWindow() int {
+	if o.Window > 0 {
+		return o.Window
+	}
+	return ollamaContextWindow
 }
 
 type ollamaRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	System string `json:"system"`
-	Stream bool   `json:"stream"`
+	Model    string         `json:"model"`
+	Messages []Message      `json:"messages"`
+	Stream   bool           `json:"stream"`
+	Options  *ollamaOptions `json:"options,omitempty"`
+}
+
+type ollamaOptions struct {
+	NumCtx int `json:"num_ctx,omitempty"`
 }
 
 type ollamaResponse struct {
-	Response string `json:"response"`
-	Done     bool   `json:"done"`
+	Message Message `json:"message"`
+	Done    bool    `json:"done"`
+	Error   string  `json:"error"` // Set instead of the message when the model fails
 }
 
 func (o *OllamaProvider) Query(ctx context.Context, systemPrompt string, userQuery string) (string, error) {
+	return o.Chat(ctx, singleTurn(systemPrompt, userQuery))
+}
+
+func (o *OllamaProvider) Stream(ctx context.Context, systemPrompt string, userQuery string, onChunk func(string)) (string, error) {
+	return o.ChatStream(ctx, singleTurn(systemPrompt, userQuery), onChunk)
+}
+
+func (o *OllamaProvider) newRequest(ctx context.Context, messages []Message, stream bool) (*http.Request, error) {
 	reqBody := ollamaRequest{
-		Model:  o.Model,
-		Prompt: userQuery,
-		System: systemPrompt,
-		Stream: false,
+		Model:    o.Model,
+		Messages: messages,
+		Stream:   stream,
+	}
+	if o.Window > 0 {
+		reqBody.Options = &ollamaOptions{NumCtx: o.Window}
 	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	apiURL := fmt.Sprintf("%s/api/generate", o.Host)
+	apiURL := fmt.Sprintf("%s/api/chat", o.Host)
 	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// Models lists the models pulled into Ollama, from /api/tags.
+func (o *OllamaProvider) Models(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", o.Host+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.http.do(req, "ollama", o.Name(), "", false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var parsed struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+	models := make([]string, 0, len(parsed.Models))
+	for _, m := range parsed.Models {
+		models = append(models, m.Name)
+	}
+	return models, nil
+}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+func (o *OllamaProvider) Chat(ctx context.Context, messages []Message) (string, error) {
+	req, err := o.newRequest(ctx, messages, false)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := o.http.do(req, "ollama", o.Name(), o.Model, false)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ollama API error: status %d", resp.StatusCode)
+	var parsedResp ollamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsedResp); err != nil {
+		return "", err
+	}
+	if parsedResp.Error != "" {
+		return "", fmt.Errorf("ollama error: %s", parsedResp.Error)
+	}
+
+	return parsedResp.Message.Content, nil
+}
+
+func (o *OllamaProvider) ChatStream(ctx context.Context, messages []Message, onChunk func(string)) (string, error) {
+	req, err := o.newRequest(ctx, messages, true)
+	if err != nil {
+		return "", err
 	}
 
-	var startResp ollamaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&startResp); err != nil {
+	resp, err := o.http.do(req, "ollama", o.Name(), o.Model, true)
+	if err != nil {
 		return "", err
 	}
+	defer resp.Body.Close()
+
+	// Ollama streams newline-delimited JSON objects, the last one has done=true
+	var full strings.Builder
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var chunk ollamaResponse
+		if err := decoder.Decode(&chunk); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return full.String(), err
+		}
+		// Errors after the 200 status, like running out of memory while
+		// loading the model, come as an object of their own
+		if chunk.Error != "" {
+			return full.String(), fmt.Errorf("ollama stream error: %s", chunk.Error)
+		}
+		if text := chunk.Message.Content; text != "" {
+			full.WriteString(text)
+			if onChunk != nil {
+				onChunk(text)
+			}
+		}
+		if chunk.Done {
+			break
+		}
+	}
 
-	return startResp.Response, nil
+	return full.String(), nil
 }
@@ -0,0 +1,191 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OpenAICompatibleProvider talks to any server implementing the OpenAI chat
+// completions protocol (vLLM, LM Studio, llama.cpp's server, ...). The
+// OpenAI and OpenRouter providers are presets on top of it.
+type OpenAICompatibleProvider struct {
+	ProviderName string // Configured name
+	Type         string // openai_compatible, or the preset's type
+	BaseURL      string // e.g. http://localhost:8000/v1
+	APIKey       string // Optional for local servers
+	Model        string
+	Organization string
+	Project      string
+	Headers      map[string]string
+	Window       int // Context window in tokens, 0 looks the model up
+
+	http *transport
+}
+
+func NewOpenAICompatibleProvider(baseURL, apiKey, model string) *OpenAICompatibleProvider {
+	return &OpenAICompatibleProvider{
+		ProviderName: "openai_compatible",
+		Type:         "openai_compatible",
+		BaseURL:      baseURL,
+		APIKey:       apiKey,
+		Model:        model,
+		Headers:      map[string]string{},
+		http:         defaultTransport(),
+	}
+}
+
+func (o *OpenAICompatibleProvider) Name() string {
+	return o.ProviderName
+}
+
+func (o *OpenAICompatibleProvider) ModelName() string {
+	return o.Model
+}
+
+func (o *OpenAICompatibleProvider) ContextWindow() int {
+	if o.Window > 0 {
+		return o.Window
+	}
+	return modelContextWindow(o.Model)
+}
+
+type openAIRequest struct {
+	Model    string    `json:"model,omitempty"`
+	Messages []Message `json:"messages"`
+	Stream   bool      `json:"stream,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message Message `json:"message"`
+	} `json:"choices"`
+}
+
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta Message `json:"delta"`
+	} `json:"choices"`
+}
+
+func (o *OpenAICompatibleProvider) Query(ctx context.Context, systemPrompt string, userQuery string) (string, error) {
+	return o.Chat(ctx, singleTurn(systemPrompt, userQuery))
+}
+
+func (o *OpenAICompatibleProvider) Stream(ctx context.Context, systemPrompt string, userQuery string, onChunk func(string)) (string, error) {
+	return o.ChatStream(ctx, singleTurn(systemPrompt, userQuery), onChunk)
+}
+
+func (o *OpenAICompatibleProvider) newRequest(ctx context.Context, messages []Message, stream bool) (*http.Request, error) {
+	reqBody := openAIRequest{
+		Model:    o.Model,
+		Messages: messages,
+		Stream:   stream,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	apiURL := strings.TrimRight(o.BaseURL, "/") + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	o.setHeaders(req)
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	return req, nil
+}
+
+// setHeaders adds authentication and the configured extra headers.
+func (o *OpenAICompatibleProvider) setHeaders(req *http.Request) {
+	if o.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+	if o.Organization != "" {
+		req.Header.Set("OpenAI-Organization", o.Organization)
+	}
+	if o.Project != "" {
+		req.Header.Set("OpenAI-Project", o.Project)
+	}
+	for k, v := range o.Headers {
+		req.Header.Set(k, v)
+	}
+}
+
+// Models lists the models the server offers, from /models.
+func (o *OpenAICompatibleProvider) Models(ctx context.Context) ([]string, error) {
+	apiURL := strings.TrimRight(o.BaseURL, "/") + "/models"
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	o.setHeaders(req)
+	return listModels(o.http, req, o.Type, o.Name())
+}
+
+func (o *OpenAICompatibleProvider) Chat(ctx context.Context, messages []Message) (string, error) {
+	req, err := o.newRequest(ctx, messages, false)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := o.http.do(req, o.Type, o.Name(), o.Model, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var parsedResp openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsedResp); err != nil {
+		return "", err
+	}
+
+	if len(parsedResp.Choices) == 0 {
+		return "", fmt.Errorf("%s returned no choices", o.Name())
+	}
+
+	return parsedResp.Choices[0].Message.Content, nil
+}
+
+func (o *OpenAICompatibleProvider) ChatStream(ctx context.Context, messages []Message, onChunk func(string)) (string, error) {
+	req, err := o.newRequest(ctx, messages, true)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := o.http.do(req, o.Type, o.Name(), o.Model, true)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	err = readSSE(resp.Body, func(data string) error {
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return err
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			return nil
+		}
+		text := chunk.Choices[0].Delta.Content
+		full.WriteString(text)
+		if onChunk != nil {
+			onChunk(text)
+		}
+		return nil
+	})
+	if err != nil {
+		return full.String(), err
+	}
+
+	return full.String(), nil
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"huh/internal/config"
+)
+
+func TestOpenAICompatibleChat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("path = %s, want /v1/chat/completions", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("Authorization = %q, want none without api_key", got)
+		}
+		if got := r.Header.Get("OpenAI-Project"); got != "proj_1" {
+			t.Errorf("OpenAI-Project = %q, want proj_1", got)
+		}
+		if got := r.Header.Get("X-Request-Source"); got != "huh" {
+			t.Errorf("X-Request-Source = %q, want huh", got)
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"df -h"}}]}`)
+	}))
+	defer server.Close()
+
+	config.AppConfig.Providers = map[string]config.ProviderConfig{
+		"local": {
+			Type: "openai_compatible",
+			Params: map[string]string{
+				"base_url":                server.URL + "/v1/",
+				"model":                   "qwen",
+				"project":                 "proj_1",
+				"header_x-request-source": "huh",
+			},
+		},
+	}
+
+	p, err := NewProvider("local")
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	if p.Name() != "local" {
+		t.Errorf("Name() = %q, want the configured name local", p.Name())
+	}
+
+	got, err := p.Query(context.Background(), "sys", "disk space")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if got != "df -h" {
+		t.Errorf("Query() = %q, want %q", got, "df -h")
+	}
+}
+
+func TestOpenAICompatibleChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, part := range []string{"du ", "-sh"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", part)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := NewOpenAICompatibleProvider(server.URL, "key", "m")
+	got, err := p.ChatStream(context.Background(), singleTurn("sys", "q"), nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	if got != "du -sh" {
+		t.Errorf("ChatStream() = %q, want %q", got, "du -sh")
+	}
+}
+
+func TestOpenAICompatibleMissingBaseURL(t *testing.T) {
+	config.AppConfig.Providers = map[string]config.ProviderConfig{
+		"local": {Type: "openai_compatible", Params: map[string]string{"model": "qwen"}},
+	}
+	if _, err := NewProvider("local"); err == nil {
+		t.Error("NewProvider() expected error for missing base_url")
+	}
+}
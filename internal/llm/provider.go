@@ -2,7 +2,36 @@ package llm
 
 import "context"
 
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is a single turn of a conversation.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
 type LLM interface {
 	Name() string
+	// ModelName is the model the provider sends requests to.
+	ModelName() string
 	Query(ctx context.Context, systemPrompt string, userQuery string) (string, error)
+	// Stream behaves like Query but calls onChunk with each piece of the
+	// answer as it arrives. The full answer is returned once the stream ends.
+	Stream(ctx context.Context, systemPrompt string, userQuery string, onChunk func(string)) (string, error)
+	// Chat sends a whole conversation and returns the assistant's reply.
+	Chat(ctx context.Context, messages []Message) (string, error)
+	// ChatStream is the streaming variant of Chat.
+	ChatStream(ctx context.Context, messages []Message, onChunk func(string)) (string, error)
+}
+
+// singleTurn builds the message list for a one-shot Query or Stream.
+func singleTurn(systemPrompt, userQuery string) []Message {
+	return []Message{
+		{Role: RoleSystem, Content: systemPrompt},
+		{Role: RoleUser, Content: userQuery},
+	}
 }
@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"slices"
+	"sync"
+
+	"huh/internal/config"
+	"huh/internal/redact"
+)
+
+// Redacted returns what was masked in the last request p sent, if p
+// redacts at all.
+func Redacted(p LLM) []redact.Finding {
+	r, ok := p.(interface{ Redacted() []redact.Finding })
+	if !ok {
+		return nil
+	}
+	return r.Redacted()
+}
+
+// PreviewRedaction returns what p would mask in text, before anything is
+// sent. It is empty if p doesn't redact.
+func PreviewRedaction(p LLM, text string) []redact.Finding {
+	r, ok := p.(interface {
+		PreviewRedaction(string) []redact.Finding
+	})
+	if !ok {
+		return nil
+	}
+	return r.PreviewRedaction(text)
+}
+
+// RedactingProvider masks secrets in the user's messages, which carry the
+// attachments, before the wrapped provider sends them anywhere.
+type RedactingProvider struct {
+	inner    LLM
+	redactor *redact.Redactor
+
+	mu       sync.Mutex
+	findings []redact.Finding // Of the last request
+}
+
+func NewRedactingProvider(p LLM, redactor *redact.Redactor) *RedactingProvider {
+	return &RedactingProvider{
+		inner:    p,
+		redactor: redactor,
+	}
+}
+
+func (r *RedactingProvider) Name() string {
+	return r.inner.Name()
+}
+
+func (r *RedactingProvider) ModelName() string {
+	return r.inner.ModelName()
+}
+
+func (r *RedactingProvider) ContextWindow() int {
+	return ContextWindow(r.inner)
+}
+
+func (r *RedactingProvider) Models(ctx context.Context) ([]string, error) {
+	return Models(ctx, r.inner)
+}
+
+// Redacted returns what was masked in the last request.
+func (r *RedactingProvider) Redacted() []redact.Finding {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.findings
+}
+
+// PreviewRedaction returns what would be masked in text.
+func (r *RedactingProvider) PreviewRedaction(text string) []redact.Finding {
+	_, findings := r.redactor.Redact(text)
+	return findings
+}
+
+func (r *RedactingProvider) Query(ctx context.Context, systemPrompt string, userQuery string) (string, error) {
+	return r.Chat(ctx, singleTurn(systemPrompt, userQuery))
+}
+
+func (r *RedactingProvider) Stream(ctx context.Context, systemPrompt string, userQuery string, onChunk func(string)) (string, error) {
+	return r.ChatStream(ctx, singleTurn(systemPrompt, userQuery), onChunk)
+}
+
+func (r *RedactingProvider) Chat(ctx context.Context, messages []Message) (string, error) {
+	return r.inner.Chat(ctx, r.redact(messages))
+}
+
+func (r *RedactingProvider) ChatStream(ctx context.Context, messages []Message, onChunk func(string)) (string, error) {
+	return r.inner.ChatStream(ctx, r.redact(messages), onChunk)
+}
+
+// redact returns a copy of messages with everything but the model's turns
+// masked. The system prompt is included, it quotes the question. The whole
+// conversation is sent every time, so earlier turns are masked again too.
+func (r *RedactingProvider) redact(messages []Message) []Message {
+	var findings []redact.Finding
+	out := make([]Message, len(messages))
+	for i, m := range messages {
+		if m.Role != RoleAssistant {
+			var found []redact.Finding
+			m.Content, found = r.redactor.Redact(m.Content)
+			for _, f := range found {
+				// A question quoted in the system prompt is found twice
+				if !slices.Contains(findings, f) {
+					findings = append(findings, f)
+				}
+			}
+		}
+		out[i] = m
+	}
+	r.mu.Lock()
+	r.findings = findings
+	r.mu.Unlock()
+	return out
+}
+
+// isLocal reports whether a provider runs on this machine, so nothing
+// leaves it. A remote Ollama server gets redacted requests like any other.
+func isLocal(cfg config.ProviderConfig) bool {
+	switch cfg.Type {
+	case "ollama":
+		host := cfg.Params["host"]
+		if host == "" {
+			return true // Defaults to localhost
+		}
+		return isLoopbackURL(host)
+	case "openai_compatible":
+		return isLoopbackURL(cfg.Params["base_url"])
+	}
+	return false
+}
+
+// isLoopbackURL reports whether rawURL points at this machine.
+func isLoopbackURL(rawURL string) bool {
+	expanded, _ := config.ExpandEnv(rawURL)
+	u, err := url.Parse(expanded)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"huh/internal/config"
+	"huh/internal/redact"
+)
+
+func TestRedactingProvider(t *testing.T) {
+	redactor, err := redact.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := &echoLLM{}
+	p := NewRedactingProvider(inner, redactor)
+
+	messages := []Message{
+		{Role: RoleSystem, Content: "User Query: 'why does DB_PASSWORD=hunter2 fail?'"},
+		{Role: RoleUser, Content: "why does DB_PASSWORD=hunter2 fail?\n--- File: .env ---\nDB_PASSWORD=hunter2\n"},
+		{Role: RoleAssistant, Content: "Quote it: PASSWORD='x'"},
+	}
+	if _, err := p.Chat(context.Background(), messages); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := inner.seen[0]
+	for _, m := range sent[:2] {
+		if strings.Contains(m.Content, "hunter2") {
+			t.Errorf("secret was sent in the %s message: %q", m.Role, m.Content)
+		}
+	}
+	if sent[2].Content != messages[2].Content {
+		t.Errorf("the model's answer changed to %q", sent[2].Content)
+	}
+	if !strings.Contains(messages[1].Content, "hunter2") {
+		t.Error("the caller's messages were modified")
+	}
+	if got := Redacted(p); len(got) != 1 || got[0].Kind != "password" {
+		t.Errorf("Redacted() = %v, want one password", got)
+	}
+
+	// A preview finds the same without sending or recording anything
+	if got := PreviewRedaction(p, "DB_PASSWORD=hunter2"); len(got) != 1 || got[0].Kind != "password" {
+		t.Errorf("PreviewRedaction() = %v, want one password", got)
+	}
+	if len(inner.seen) != 1 {
+		t.Errorf("PreviewRedaction() sent a request")
+	}
+	if got := PreviewRedaction(inner, "DB_PASSWORD=hunter2"); got != nil {
+		t.Errorf("PreviewRedaction() of a provider that doesn't redact = %v", got)
+	}
+
+	// Findings are per request
+	p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}})
+	if got := Redacted(p); len(got) != 0 {
+		t.Errorf("Redacted() after a clean request = %v", got)
+	}
+}
+
+func TestIsLocal(t *testing.T) {
+	tests := []struct {
+		cfg  config.ProviderConfig
+		want bool
+	}{
+		{config.ProviderConfig{Type: "ollama"}, true},
+		{config.ProviderConfig{Type: "ollama", Params: map[string]string{"host": "http://localhost:11434"}}, true},
+		{config.ProviderConfig{Type: "ollama", Params: map[string]string{"host": "http://127.0.0.1:11434"}}, true},
+		{config.ProviderConfig{Type: "ollama", Params: map[string]string{"host": "http://gpu-box:11434"}}, false},
+		{config.ProviderConfig{Type: "openai_compatible", Params: map[string]string{"base_url": "http://localhost:8000/v1"}}, true},
+		{config.ProviderConfig{Type: "openai_compatible", Params: map[string]string{"base_url": "http://127.0.0.1:1234/v1"}}, true},
+		{config.ProviderConfig{Type: "openai_compatible", Params: map[string]string{"base_url": "http://[::1]:8080/v1"}}, true},
+		{config.ProviderConfig{Type: "openai_compatible", Params: map[string]string{"base_url": "https://api.together.xyz/v1"}}, false},
+		{config.ProviderConfig{Type: "openai", Params: map[string]string{"api_key": "sk-test"}}, false},
+		{config.ProviderConfig{Type: "anthropic"}, false},
+	}
+	for _, tt := range tests {
+		if got := isLocal(tt.cfg); got != tt.want {
+			t.Errorf("isLocal(%v) = %v, want %v", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestNewProviderRedacts(t *testing.T) {
+	saved := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = saved })
+
+	config.AppConfig.Providers = map[string]config.ProviderConfig{
+		"ollama": {Type: "ollama"},
+		"openai": {Type: "openai", Params: map[string]string{"api_key": "sk-test"}},
+	}
+	config.AppConfig.Redact = config.RedactConfig{Enabled: true}
+
+	if p, _ := NewProvider("openai"); !isRedacting(p) {
+		t.Errorf("openai = %T, want redaction", p)
+	}
+	if p, _ := NewProvider("ollama"); isRedacting(p) {
+		t.Error("ollama is redacted by default")
+	}
+
+	config.AppConfig.Redact.Local = true
+	if p, _ := NewProvider("ollama"); !isRedacting(p) {
+		t.Error("ollama not redacted with local: true")
+	}
+
+	config.AppConfig.Redact.Patterns = []string{"("}
+	if _, err := NewProvider("ollama"); err == nil {
+		t.Error("NewProvider() accepted an invalid pattern")
+	}
+}
+
+func isRedacting(p LLM) bool {
+	_, ok := p.(*RedactingProvider)
+	return ok
+}
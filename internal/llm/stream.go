@@ -0,0 +1,33 @@
+package llm
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// readSSE reads a Server-Sent Events body and calls fn with the payload of
+// every "data:" line. Reading stops at the "[DONE]" sentinel used by
+// OpenAI-style APIs, at EOF, or when fn returns an error.
+func readSSE(r io.Reader, fn func(data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			// Comments (": keep-alive"), event names and blank separators
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return nil
+		}
+		if data == "" {
+			continue
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadSSE(t *testing.T) {
+	body := ": keep-alive\n\n" +
+		"data: {\"a\":1}\n\n" +
+		"event: ping\n" +
+		"data:{\"a\":2}\n\n" +
+		"data: [DONE]\n\n" +
+		"data: {\"a\":3}\n\n"
+
+	var got []string
+	err := readSSE(strings.NewReader(body), func(data string) error {
+		got = append(got, data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readSSE() error = %v", err)
+	}
+	want := []string{`{"a":1}`, `{"a":2}`}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("readSSE() payloads = %v, want %v", got, want)
+	}
+}
+
+func TestOllamaStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		for _, part := range []string{"ls ", "-la"} {
+			fmt.Fprintf(w, "{\"message\":{\"role\":\"assistant\",\"content\":%q},\"done\":false}\n", part)
+		}
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true}`)
+	}))
+	defer server.Close()
+
+	var chunks []string
+	p := NewOllamaProvider(server.URL, "test-model")
+	got, err := p.Stream(context.Background(), "sys", "list files", func(c string) {
+		chunks = append(chunks, c)
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if got != "ls -la" {
+		t.Errorf("Stream() = %q, want %q", got, "ls -la")
+	}
+	if len(chunks) != 2 {
+		t.Errorf("Stream() delivered %d chunks, want 2", len(chunks))
+	}
+}
+
+func TestOllamaStreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"ls "},"done":false}`)
+		fmt.Fprintln(w, `{"error":"model requires more system memory"}`)
+	}))
+	defer server.Close()
+
+	p := NewOllamaProvider(server.URL, "test-model")
+	got, err := p.Stream(context.Background(), "sys", "list files", nil)
+	if err == nil || !strings.Contains(err.Error(), "more system memory") {
+		t.Errorf("Stream() error = %v, want the error from the stream", err)
+	}
+	if got != "ls " {
+		t.Errorf("Stream() = %q, want the text before the error", got)
+	}
+}
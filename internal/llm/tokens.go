@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// BytesPerToken is the rough average of ASCII text the token estimate
+	// is based on.
+	BytesPerToken = 4
+	// tokensPerRune is what each non-ASCII character is counted as.
+	tokensPerRune = 2
+	// defaultContextWindow is assumed for models we know nothing about.
+	defaultContextWindow = 8192
+	// ollamaContextWindow is Ollama's default num_ctx. Anything beyond it is
+	// silently dropped, so it's worth raising with the context_window param.
+	ollamaContextWindow = 4096
+	// reservedTokens are kept free for the system prompt, the question and
+	// the answer.
+	reservedTokens = 1536
+	// minAttachmentTokens is the smallest budget handed out, even for tiny
+	// windows.
+	minAttachmentTokens = 512
+)
+
+// modelContextWindows maps model name prefixes to their context window in
+// tokens. The first match wins, so longer prefixes come first.
+var modelContextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4.1", 1047576},
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"o1", 128000},
+	{"o3", 200000},
+	{"o4", 200000},
+	{"claude", 200000},
+	{"gemini", 1000000},
+	{"llama-3.1", 128000},
+	{"llama-3.2", 128000},
+	{"llama-3.3", 128000},
+	{"llama3.1", 128000},
+	{"llama3.2", 128000},
+	{"llama3.3", 128000},
+	{"llama3", 8192},
+	{"qwen2.5", 32768},
+	{"qwen3", 32768},
+	{"mistral", 32768},
+	{"mixtral", 32768},
+	{"deepseek", 65536},
+}
+
+// modelContextWindow looks model up in modelContextWindows. Vendor
+// prefixes like "anthropic/" are ignored.
+func modelContextWindow(model string) int {
+	model = strings.ToLower(model)
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	for _, m := range modelContextWindows {
+		if strings.HasPrefix(model, m.prefix) {
+			return m.tokens
+		}
+	}
+	return defaultContextWindow
+}
+
+// contextWindowParam reads the optional "context_window" param, 0 if unset.
+func contextWindowParam(params map[string]string) (int, error) {
+	v := params["context_window"]
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid context_window %q (use a number of tokens)", v)
+	}
+	return n, nil
+}
+
+// EstimateTokens guesses how many tokens text takes. Tokenizers average
+// about four bytes per token for English and code, but only one to two
+// characters per token for CJK and other non-Latin scripts, whatever their
+// byte length. So non-ASCII characters count as two tokens each, which
+// errs high for common tokenizers.
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for i := 0; i < len(text); {
+		if text[i] < utf8.RuneSelf {
+			ascii++
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		other++
+		i += size
+	}
+	return (ascii+BytesPerToken-1)/BytesPerToken + other*tokensPerRune
+}
+
+// ContextWindow returns how many tokens p's model accepts.
+func ContextWindow(p LLM) int {
+	if c, ok := p.(interface{ ContextWindow() int }); ok {
+		if n := c.ContextWindow(); n > 0 {
+			return n
+		}
+	}
+	return defaultContextWindow
+}
+
+// AttachmentBudget returns how many tokens of attachments fit in p's
+// context window next to the prompt and the answer.
+func AttachmentBudget(p LLM) int {
+	window := ContextWindow(p)
+	return max(window-window/8-reservedTokens, minAttachmentTokens)
+}
@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"testing"
+
+	"huh/internal/config"
+)
+
+func TestContextWindow(t *testing.T) {
+	saved := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = saved })
+
+	config.AppConfig.Providers = map[string]config.ProviderConfig{
+		"ollama":     {Type: "ollama"},
+		"big-ollama": {Type: "ollama", Params: map[string]string{"context_window": "32768"}},
+		"openai":     {Type: "openai", Params: map[string]string{"api_key": "sk-test", "model": "gpt-4o-mini"}},
+		"openrouter": {Type: "openrouter", Params: map[string]string{"api_key": "sk-or", "model": "anthropic/claude-3-opus"}},
+		"local":      {Type: "openai_compatible", Params: map[string]string{"base_url": "http://localhost:8000/v1", "model": "my-finetune"}},
+		"bad":        {Type: "ollama", Params: map[string]string{"context_window": "lots"}},
+	}
+
+	tests := []struct {
+		name string
+		want int
+	}{
+		{"ollama", ollamaContextWindow},
+		{"big-ollama", 32768},
+		{"openai", 128000},
+		{"openrouter", 200000},
+		{"local", defaultContextWindow},
+	}
+	for _, tt := range tests {
+		p, err := NewProvider(tt.name)
+		if err != nil {
+			t.Fatalf("NewProvider(%s) error = %v", tt.name, err)
+		}
+		if got := ContextWindow(p); got != tt.want {
+			t.Errorf("ContextWindow(%s) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := NewProvider("bad"); err == nil {
+		t.Error("NewProvider() accepted an invalid context_window")
+	}
+
+	// A chain has to fit its smallest window
+	config.AppConfig.DefaultProvider = "openai"
+	config.AppConfig.Fallback = []string{"ollama"}
+	p, err := NewProvider("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ContextWindow(p); got != ollamaContextWindow {
+		t.Errorf("ContextWindow(chain) = %d, want %d", got, ollamaContextWindow)
+	}
+}
+
+func TestAttachmentBudget(t *testing.T) {
+	tests := []struct {
+		window int
+		want   int
+	}{
+		{4096, 4096 - 512 - reservedTokens},
+		{128000, 128000 - 16000 - reservedTokens},
+		{1024, minAttachmentTokens},
+	}
+	for _, tt := range tests {
+		p := &OllamaProvider{Window: tt.window}
+		if got := AttachmentBudget(p); got != tt.want {
+			t.Errorf("AttachmentBudget(%d) = %d, want %d", tt.window, got, tt.want)
+		}
+	}
+
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"twelve bytes", 3},
+		{"ls -la", 2},
+		{"磁盘空间不足", 12},      // 18 bytes, tokenizers take 6 to 12 tokens
+		{"café au lait", 5}, // 11 ASCII bytes and an é
+		{"👍", 2},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 2
+	retryBaseDelay    = 500 * time.Millisecond
+	retryMaxDelay     = 8 * time.Second
+	// A server asking us to wait longer than this is treated as a hard
+	// failure rather than leaving the user staring at the spinner.
+	maxRetryAfter = 30 * time.Second
+)
+
+// transport sends provider HTTP requests. Transient failures (429, 502, 503,
+// 504 and dropped connections) are retried with jittered exponential
+// backoff, honoring Retry-After. Other non-200 responses become an
+// *APIError carrying the provider's error message.
+type transport struct {
+	Timeout    time.Duration
+	MaxRetries int
+
+	client       *http.Client // Blocking requests, Timeout covers the whole exchange
+	streamClient *http.Client // Streams, Timeout only covers waiting for the response
+
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func newTransport(timeout time.Duration, maxRetries int) *transport {
+	streamTransport := http.DefaultTransport.(*http.Transport).Clone()
+	streamTransport.ResponseHeaderTimeout = timeout
+
+	return &transport{
+		Timeout:      timeout,
+		MaxRetries:   maxRetries,
+		client:       &http.Client{Timeout: timeout},
+		streamClient: &http.Client{Transport: streamTransport},
+		sleep:        sleepContext,
+	}
+}
+
+func defaultTransport() *transport {
+	return newTransport(defaultTimeout, defaultMaxRetries)
+}
+
+// transportFromParams builds a transport from the optional "timeout"
+// (a Go duration like "90s") and "max_retries" provider params.
+func transportFromParams(params map[string]string) (*transport, error) {
+	timeout := defaultTimeout
+	if v := params["timeout"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q (use a duration like 60s)", v)
+		}
+		timeout = d
+	}
+
+	maxRetries := defaultMaxRetries
+	if v := params["max_retries"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid max_retries %q", v)
+		}
+		maxRetries = n
+	}
+
+	return newTransport(timeout, maxRetries), nil
+}
+
+// do sends req and returns the response if the status is 200. The caller
+// must close the response body. Failures are returned as *APIError or
+// *RequestError; the provider's type and configured name, and the model,
+// are recorded on them.
+func (t *transport) do(req *http.Request, typ, provider, model string, stream bool) (*http.Response, error) {
+	client := t.client
+	if stream {
+		client = t.streamClient
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if attempt >= t.MaxRetries || !isRetryableNetError(err) {
+				return nil, newRequestError(typ, provider, req, err)
+			}
+			if err := t.sleep(req.Context(), backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+
+		apiErr := newAPIError(typ, provider, resp)
+		apiErr.Model = model
+		resp.Body.Close()
+
+		if attempt >= t.MaxRetries || !isRetryableStatus(resp.StatusCode) || apiErr.RetryAfter > maxRetryAfter {
+			return nil, apiErr
+		}
+
+		delay := backoff(attempt)
+		if apiErr.RetryAfter > 0 {
+			delay = apiErr.RetryAfter
+		}
+		if err := t.sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isRetryableNetError reports whether the connection was dropped under us.
+// Refused connections and timeouts are not retried, waiting again would
+// only delay the error (or the fallback provider).
+func isRetryableNetError(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// backoff returns a full-jitter exponential delay for the given attempt.
+func backoff(attempt int) time.Duration {
+	d := retryBaseDelay << attempt
+	if d > retryMaxDelay || d <= 0 {
+		d = retryMaxDelay
+	}
+	return time.Duration(rand.Int64N(int64(d))) + retryBaseDelay/2
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRetryAfter understands both forms of the Retry-After header:
+// delay-seconds and an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// newAPIError reads the error body of resp. Providers disagree on the
+// shape: Ollama sends {"error": "..."}, OpenAI-style APIs and Anthropic
+// send {"error": {"message": "...", "type": "..."}}.
+func newAPIError(typ, provider string, resp *http.Response) *APIError {
+	apiErr := &APIError{
+		Provider:   provider,
+		Type:       typ,
+		Endpoint:   endpoint(resp.Request),
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	apiErr.Message = errorMessage(body)
+	apiErr.Kind = classifyStatus(apiErr.StatusCode, apiErr.Message)
+	return apiErr
+}
+
+// newRequestError wraps a failure to get any response. Cancellation by the
+// caller is passed through untouched.
+func newRequestError(typ, provider string, req *http.Request, err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	kind := ErrNetwork
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		kind = ErrTimeout
+	}
+	return &RequestError{
+		Provider: provider,
+		Type:     typ,
+		Endpoint: endpoint(req),
+		Kind:     kind,
+		Err:      err,
+	}
+}
+
+func endpoint(req *http.Request) string {
+	if req == nil || req.URL == nil {
+		return ""
+	}
+	return req.URL.Scheme + "://" + req.URL.Host
+}
+
+func errorMessage(body []byte) string {
+	var parsed struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		// Not JSON, a short plain-text body is still better than nothing
+		text := strings.TrimSpace(string(body))
+		if text != "" && len(text) <= 200 && !strings.HasPrefix(text, "<") {
+			return text
+		}
+		return ""
+	}
+
+	if len(parsed.Error) > 0 {
+		var s string
+		if err := json.Unmarshal(parsed.Error, &s); err == nil {
+			return s
+		}
+		var obj struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+		}
+		if err := json.Unmarshal(parsed.Error, &obj); err == nil && obj.Message != "" {
+			if obj.Type != "" {
+				return fmt.Sprintf("%s (%s)", obj.Message, obj.Type)
+			}
+			return obj.Message
+		}
+	}
+	return parsed.Message
+}
@@ -0,0 +1,180 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestTransport returns a transport that records delays instead of sleeping.
+func newTestTransport(maxRetries int) (*transport, *[]time.Duration) {
+	var delays []time.Duration
+	t := newTransport(time.Second, maxRetries)
+	t.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return t, &delays
+}
+
+func TestTransportRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		responses  []func(w http.ResponseWriter)
+		maxRetries int
+		wantErr    string
+		wantCalls  int
+		wantDelays []time.Duration // nil means "don't check exact values"
+	}{
+		{
+			name: "503 then success",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) },
+				func(w http.ResponseWriter) { fmt.Fprint(w, "ok") },
+			},
+			maxRetries: 2,
+			wantCalls:  2,
+		},
+		{
+			name: "429 honors Retry-After",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("Retry-After", "3")
+					w.WriteHeader(http.StatusTooManyRequests)
+				},
+				func(w http.ResponseWriter) { fmt.Fprint(w, "ok") },
+			},
+			maxRetries: 2,
+			wantCalls:  2,
+			wantDelays: []time.Duration{3 * time.Second},
+		},
+		{
+			name: "long Retry-After is not waited for",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("Retry-After", "3600")
+					w.WriteHeader(http.StatusTooManyRequests)
+				},
+			},
+			maxRetries: 2,
+			wantErr:    "status 429",
+			wantCalls:  1,
+			wantDelays: []time.Duration{},
+		},
+		{
+			name: "404 is not retried and carries the message",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.WriteHeader(http.StatusNotFound)
+					fmt.Fprint(w, `{"error":"model \"llama9\" not found, try pulling it first"}`)
+				},
+			},
+			maxRetries: 2,
+			wantErr:    `status 404: model "llama9" not found`,
+			wantCalls:  1,
+		},
+		{
+			name: "gives up after max retries",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) },
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) },
+			},
+			maxRetries: 1,
+			wantErr:    "status 502",
+			wantCalls:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// The body must be re-sent on every attempt
+				if b, _ := io.ReadAll(r.Body); string(b) != "body" {
+					t.Errorf("attempt %d: request body = %q, want %q", calls+1, b, "body")
+				}
+				tt.responses[calls](w)
+				calls++
+			}))
+			defer server.Close()
+
+			tr, delays := newTestTransport(tt.maxRetries)
+			req, _ := http.NewRequest("POST", server.URL, strings.NewReader("body"))
+			resp, err := tr.do(req, "test", "test", "m", false)
+			if resp != nil {
+				resp.Body.Close()
+			}
+
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("do() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("do() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("server called %d times, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantDelays != nil && fmt.Sprint(*delays) != fmt.Sprint(tt.wantDelays) {
+				t.Errorf("delays = %v, want %v", *delays, tt.wantDelays)
+			}
+		})
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"error":"model not found"}`, "model not found"},
+		{`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error"}}`, "Incorrect API key provided (invalid_request_error)"},
+		{`{"error":{"message":"No endpoints found","code":404}}`, "No endpoints found"},
+		{`{"message":"Bad things"}`, "Bad things"},
+		{"upstream connect error", "upstream connect error"},
+		{"<html><body>502 Bad Gateway</body></html>", ""},
+	}
+	for _, tt := range tests {
+		if got := errorMessage([]byte(tt.body)); got != tt.want {
+			t.Errorf("errorMessage(%s) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestTransportFromParams(t *testing.T) {
+	tr, err := transportFromParams(map[string]string{"timeout": "90s", "max_retries": "0"})
+	if err != nil {
+		t.Fatalf("transportFromParams() error = %v", err)
+	}
+	if tr.Timeout != 90*time.Second || tr.MaxRetries != 0 {
+		t.Errorf("transport = %v/%d, want 90s/0", tr.Timeout, tr.MaxRetries)
+	}
+
+	if _, err := transportFromParams(map[string]string{"timeout": "soon"}); err == nil {
+		t.Error("transportFromParams() expected error for invalid timeout")
+	}
+}
+
+func TestTransportContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tr := newTransport(time.Second, 5)
+	tr.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return sleepContext(ctx, d)
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, "POST", server.URL, nil)
+	if _, err := tr.do(req, "test", "test", "m", false); !errors.Is(err, context.Canceled) {
+		t.Errorf("do() error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,182 @@
+// Package redact masks secrets in attachments before they are sent to a
+// provider that runs on someone else's machine.
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Finding is one secret that was replaced.
+type Finding struct {
+	Kind   string // e.g. "AWS access key"
+	Sample string // Masked preview, e.g. "AKIA…MPLE"
+}
+
+func (f Finding) String() string {
+	return f.Kind + " " + f.Sample
+}
+
+// rule finds one kind of secret. With group > 0 only that submatch is
+// replaced, so "password=hunter2" keeps its key.
+type rule struct {
+	kind  string
+	re    *regexp.Regexp
+	group int
+}
+
+var builtinRules = []rule{
+	{"private key", regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----.*?-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`), 0},
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA|AIDA|AROA|AGPA|ANPA|ANVA|AIPA)[A-Z0-9]{16}\b`), 0},
+	{"AWS secret key", regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`), 1},
+	{"GitHub token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`), 0},
+	{"API key", regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`), 0},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`), 0},
+	{"JWT", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`), 0},
+	{"password", regexp.MustCompile(`(?i)(?:\b|_)(?:password|passwd|pwd|secret|token|api_?key|access_?key|client_?secret)["']?[ \t]*[:=][ \t]*("[^"\n]+"|'[^'\n]+'|[^\s"',;&]+)`), 1},
+	{"URL password", regexp.MustCompile(`\b[a-z][a-z0-9+.-]*://[^\s:/@]+:([^\s:/@]+)@`), 1},
+}
+
+// candidateRe picks out words that could be a random token, with base64
+// padding. Slashes are left out so that paths are never candidates.
+var candidateRe = regexp.MustCompile(`[A-Za-z0-9+_-]{24,}={0,2}`)
+
+// Redactor replaces secrets with a placeholder naming what was there.
+type Redactor struct {
+	rules []rule
+}
+
+// New returns a Redactor with the built-in rules and the user's patterns.
+// Patterns are Go regular expressions, the whole match is replaced.
+func New(patterns []string) (*Redactor, error) {
+	r := &Redactor{rules: append([]rule(nil), builtinRules...)}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		r.rules = append(r.rules, rule{kind: "custom pattern", re: re})
+	}
+	return r, nil
+}
+
+// Redact returns text with every secret replaced, and what was replaced.
+func (r *Redactor) Redact(text string) (string, []Finding) {
+	var findings []Finding
+	for _, rl := range r.rules {
+		text = replace(text, rl.re, rl.group, func(secret string) string {
+			if skipValue(secret) {
+				return secret
+			}
+			findings = append(findings, Finding{Kind: rl.kind, Sample: mask(secret)})
+			return placeholder(rl.kind)
+		})
+	}
+
+	// Random looking tokens that no rule knows about
+	text = candidateRe.ReplaceAllStringFunc(text, func(word string) string {
+		if !looksRandom(word) {
+			return word
+		}
+		findings = append(findings, Finding{Kind: "high-entropy string", Sample: mask(word)})
+		return placeholder("high-entropy string")
+	})
+	return text, findings
+}
+
+// replace is ReplaceAllStringFunc for a single submatch.
+func replace(text string, re *regexp.Regexp, group int, fn func(string) string) string {
+	matches := re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[2*group], m[2*group+1]
+		if start < 0 {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(fn(text[start:end]))
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// skipValue leaves alone what an earlier rule already replaced and values
+// that are code rather than a secret, like password = os.getenv("PW").
+func skipValue(value string) bool {
+	return strings.Contains(value, "[REDACTED") ||
+		strings.ContainsAny(value, "()") ||
+		strings.HasPrefix(value, "$") || strings.HasPrefix(value, "{") || strings.HasPrefix(value, "<")
+}
+
+func placeholder(kind string) string {
+	return "[REDACTED " + kind + "]"
+}
+
+// mask keeps just enough of a secret to recognize it.
+func mask(secret string) string {
+	if strings.HasPrefix(secret, "-----BEGIN") {
+		header, _, _ := strings.Cut(secret, "\n")
+		return header
+	}
+	secret = strings.Trim(secret, `"'`)
+	if len(secret) < 12 {
+		return "****"
+	}
+	return secret[:4] + "…" + secret[len(secret)-4:]
+}
+
+// looksRandom reports whether word is likely a generated secret: mixed
+// case letters and digits with high Shannon entropy. Hex strings like git
+// hashes top out at 4 bits per character and are left alone.
+func looksRandom(word string) bool {
+	var upper, lower, digit bool
+	counts := make(map[rune]int)
+	for _, c := range word {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+		counts[c]++
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+
+	entropy := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(len(word))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= 4.2
+}
+
+// Summary counts findings per kind, e.g. "AWS access key (2), JWT".
+func Summary(findings []Finding) string {
+	var kinds []string
+	counts := make(map[string]int)
+	for _, f := range findings {
+		if counts[f.Kind] == 0 {
+			kinds = append(kinds, f.Kind)
+		}
+		counts[f.Kind]++
+	}
+	parts := make([]string, len(kinds))
+	for i, k := range kinds {
+		parts[i] = k
+		if counts[k] > 1 {
+			parts[i] += fmt.Sprintf(" (%d)", counts[k])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,237 @@
+// Package risk flags suggested commands that could do serious damage before
+// the user copies or runs them.
+package risk
+
+import (
+	"path"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+type Level int
+
+const (
+	Low Level = iota
+	Medium
+	High
+)
+
+func (l Level) String() string {
+	switch l {
+	case High:
+		return "high"
+	case Medium:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// Finding is one reason a command was flagged.
+type Finding struct {
+	Level  Level
+	Reason string
+}
+
+// Assessment is the result of analyzing a command. Level is the highest
+// level among the findings.
+type Assessment struct {
+	Level    Level
+	Findings []Finding
+}
+
+func (a *Assessment) add(level Level, reason string) {
+	for _, f := range a.Findings {
+		if f.Reason == reason {
+			return
+		}
+	}
+	a.Findings = append(a.Findings, Finding{Level: level, Reason: reason})
+	if level > a.Level {
+		a.Level = level
+	}
+}
+
+// Reasons returns the finding reasons, most severe first.
+func (a Assessment) Reasons() []string {
+	var reasons []string
+	for _, level := range []Level{High, Medium, Low} {
+		for _, f := range a.Findings {
+			if f.Level == level {
+				reasons = append(reasons, f.Reason)
+			}
+		}
+	}
+	return reasons
+}
+
+// Analyze parses command as a bash script and checks every simple command,
+// pipeline, redirection and function in it. Models like to put
+// placeholders such as <file> into commands, so if the text doesn't parse
+// it falls back to checking whitespace-separated words.
+func Analyze(command string) Assessment {
+	var a Assessment
+
+	if forkBombPattern(command) {
+		a.add(High, "fork bomb")
+	}
+
+	parser := syntax.NewParser(syntax.Variant(syntax.LangBash))
+	file, err := parser.Parse(strings.NewReader(command), "")
+	if err != nil {
+		analyzeFallback(&a, command)
+		return a
+	}
+
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.CallExpr:
+			checkCall(&a, words(n.Args))
+			checkShellSubst(&a, n)
+		case *syntax.BinaryCmd:
+			if n.Op == syntax.Pipe || n.Op == syntax.PipeAll {
+				checkPipe(&a, stmtCalls(n.X), stmtCalls(n.Y))
+			}
+		case *syntax.Redirect:
+			if n.Word != nil {
+				checkRedirect(&a, n.Op, wordString(n.Word))
+			}
+		case *syntax.FuncDecl:
+			if n.Name != nil && callsItself(n) {
+				a.add(High, "fork bomb (function that keeps spawning itself)")
+			}
+		}
+		return true
+	})
+
+	return a
+}
+
+// Commands lists the programs command runs, looking through sudo, env and
+// the other wrappers. Like Analyze, it falls back to whitespace-separated
+// words for text that doesn't parse.
+func Commands(command string) []string {
+	var names []string
+	add := func(args []string) {
+		for len(args) > 0 && strings.Contains(args[0], "=") {
+			args = args[1:] // Variable assignments, in the fallback
+		}
+		if args = unwrap(args); len(args) > 0 {
+			names = append(names, path.Base(args[0]))
+		}
+	}
+
+	parser := syntax.NewParser(syntax.Variant(syntax.LangBash))
+	file, err := parser.Parse(strings.NewReader(command), "")
+	if err != nil {
+		for _, line := range strings.Split(command, "\n") {
+			for _, stage := range splitAny(line, "&&", "||", ";", "|") {
+				add(strings.Fields(stage))
+			}
+		}
+		return names
+	}
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if call, ok := node.(*syntax.CallExpr); ok {
+			add(words(call.Args))
+		}
+		return true
+	})
+	return names
+}
+
+// analyzeFallback applies the command and pipe checks to plain words, for
+// text the parser rejected.
+func analyzeFallback(a *Assessment, command string) {
+	for _, line := range strings.Split(command, "\n") {
+		for _, chain := range splitAny(line, "&&", "||", ";") {
+			stages := strings.Split(chain, "|")
+			var prev [][]string
+			for _, stage := range stages {
+				args := strings.Fields(stage)
+				if len(args) == 0 {
+					continue
+				}
+				checkCall(a, args)
+				if prev != nil {
+					checkPipe(a, prev, [][]string{args})
+				}
+				prev = append(prev, args)
+			}
+		}
+	}
+}
+
+func splitAny(s string, seps ...string) []string {
+	parts := []string{s}
+	for _, sep := range seps {
+		var next []string
+		for _, p := range parts {
+			next = append(next, strings.Split(p, sep)...)
+		}
+		parts = next
+	}
+	return parts
+}
+
+// stmtCalls collects the argument lists of every simple command in stmt,
+// including command and process substitutions.
+func stmtCalls(stmt *syntax.Stmt) [][]string {
+	var calls [][]string
+	syntax.Walk(stmt, func(node syntax.Node) bool {
+		if call, ok := node.(*syntax.CallExpr); ok {
+			calls = append(calls, words(call.Args))
+		}
+		return true
+	})
+	return calls
+}
+
+func callsItself(fn *syntax.FuncDecl) bool {
+	found := false
+	syntax.Walk(fn.Body, func(node syntax.Node) bool {
+		if call, ok := node.(*syntax.CallExpr); ok && len(call.Args) > 0 && wordString(call.Args[0]) == fn.Name.Value {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func words(ws []*syntax.Word) []string {
+	out := make([]string, 0, len(ws))
+	for _, w := range ws {
+		out = append(out, wordString(w))
+	}
+	return out
+}
+
+// wordString renders a word the way the checks want to see it: quotes
+// removed, variables kept as $NAME and substitutions as $(...).
+func wordString(w *syntax.Word) string {
+	var b strings.Builder
+	for _, part := range w.Parts {
+		writePart(&b, part)
+	}
+	return b.String()
+}
+
+func writePart(b *strings.Builder, part syntax.WordPart) {
+	switch p := part.(type) {
+	case *syntax.Lit:
+		b.WriteString(p.Value)
+	case *syntax.SglQuoted:
+		b.WriteString(p.Value)
+	case *syntax.DblQuoted:
+		for _, inner := range p.Parts {
+			writePart(b, inner)
+		}
+	case *syntax.ParamExp:
+		if p.Param != nil {
+			b.WriteString("$" + p.Param.Value)
+		}
+	case *syntax.CmdSubst, *syntax.ProcSubst:
+		b.WriteString("$(...)")
+	}
+}
@@ -0,0 +1,133 @@
+package risk
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		command    string
+		wantLevel  Level
+		wantReason string // substring of one of the reasons, if set
+	}{
+		// Harmless
+		{"ls -la", Low, ""},
+		{"du -sh * | sort -h", Low, ""},
+		{"git push origin main", Low, ""},
+		{"echo hi > /dev/null", Low, ""},
+		{"chmod -R 755 ./public", Low, ""},
+
+		// Recursive deletes
+		{"rm -rf ./build", Medium, "recursive delete"},
+		{"rm -rf ~/*", High, "recursive delete of ~/*"},
+		{"sudo rm -r -f /", High, "recursive delete of /"},
+		{"rm --recursive --force \"$HOME\"", High, "recursive delete of $HOME"},
+		{"rm -rf --no-preserve-root /", High, "no-preserve-root"},
+		{"find . -name '*.tmp' -delete", Medium, "find -delete"},
+		{"find . -exec rm -rf {} +", Medium, "recursive delete"},
+		{"find /tmp -name x -execdir rm -r {} \\;", Medium, "recursive delete"},
+		{"find . -exec ls {} ;", Low, ""},
+		{"find . -print0 | xargs -0 rm -rf", Medium, "recursive delete"},
+		{"ls | xargs -I {} rm -rf {}", Medium, "recursive delete"},
+		{"xargs -n 1 -P 4 chmod -R 777 /", High, "world-writable"},
+
+		// Wrappers and their options
+		{"sudo -u root rm -rf /", High, "recursive delete of /"},
+		{"sudo --user=root rm -rf /", High, "recursive delete of /"},
+		{"sudo -u root -- rm -rf /", High, "recursive delete of /"},
+		{"env -u HOME LANG=C rm -rf /", High, "recursive delete of /"},
+		{"nice -n 19 rm -rf /", High, "recursive delete of /"},
+		{"timeout -s KILL 10s rm -rf /", High, "recursive delete of /"},
+		{"doas -u root dd if=/dev/zero of=/dev/sda", High, "/dev/sda"},
+		{"sudo -u deploy ls /root", Low, ""},
+
+		// Raw disk writes
+		{"dd if=ubuntu.iso of=/dev/sda bs=4M", High, "/dev/sda"},
+		{"sudo dd if=/dev/zero of=/dev/nvme0n1", High, "/dev/nvme0n1"},
+		{"dd if=/dev/zero of=disk.img bs=1M count=10", Low, ""},
+		{"cat image.iso > /dev/sdb", High, "writes directly to /dev/sdb"},
+		{"sudo mkfs.ext4 /dev/sdb1", High, "mkfs.ext4"},
+
+		// Permissions
+		{"chmod -R 777 /", High, "world-writable"},
+		{"sudo chmod -R 777 /var/www", High, "world-writable"},
+		{"chown -R nobody /etc", High, "chown -R on /etc"},
+
+		// Download and execute
+		{"curl -fsSL https://example.com/install.sh | sh", High, "into sh"},
+		{"wget -qO- https://example.com/x | sudo bash", High, "into bash"},
+		{"bash <(curl -s https://example.com/x)", High, "downloaded script"},
+		{"sh -c \"$(curl -fsSL https://example.com/x)\"", High, "downloaded script"},
+		{"curl https://example.com/data.json | jq .", Low, ""},
+
+		// Git
+		{"git push --force origin main", High, "force push"},
+		{"git push -f", High, "force push"},
+		{"git push origin +main", High, "force push of main"},
+		{"git push --force-with-lease", Medium, "with lease"},
+		{"git reset --hard HEAD~1", Medium, "reset --hard"},
+		{"git -C repo push --force", High, "force push"},
+		{"git -c user.name=x --git-dir .git push origin +main", High, "force push of main"},
+		{"git --git-dir=.git --no-pager reset --hard", Medium, "reset --hard"},
+		{"git -C push status", Low, ""},
+
+		// Fork bombs
+		{":(){ :|:& };:", High, "fork bomb"},
+		{"bomb() { bomb | bomb & }; bomb", High, "fork bomb"},
+
+		// Unparseable placeholders fall back to word checks
+		{"rm -rf <directory>", Medium, "recursive delete"},
+		{"curl <url> | sh", High, "into sh"},
+
+		// Multi-line scripts are checked line by line
+		{"cd /tmp\nrm -rf /", High, "recursive delete of /"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			got := Analyze(tt.command)
+			if got.Level != tt.wantLevel {
+				t.Errorf("Analyze(%q).Level = %v, want %v (reasons: %v)", tt.command, got.Level, tt.wantLevel, got.Reasons())
+			}
+			if tt.wantReason == "" {
+				return
+			}
+			for _, r := range got.Reasons() {
+				if strings.Contains(r, tt.wantReason) {
+					return
+				}
+			}
+			t.Errorf("Analyze(%q) reasons %v, want one containing %q", tt.command, got.Reasons(), tt.wantReason)
+		})
+	}
+}
+
+func TestReasonsOrderedBySeverity(t *testing.T) {
+	got := Analyze("git reset --hard && rm -rf /").Reasons()
+	if len(got) != 2 || !strings.Contains(got[0], "recursive delete of /") {
+		t.Errorf("Reasons() = %v, want the high risk reason first", got)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"ls -la", []string{"ls"}},
+		{"sudo -u root vim /etc/hosts", []string{"vim"}},
+		{"env -C /tmp PAGER=cat less README.md", []string{"less"}},
+		{"journalctl -u nginx | less", []string{"journalctl", "less"}},
+		{"cd /tmp && /usr/bin/htop", []string{"cd", "htop"}},
+		{`echo "a | less; top"`, []string{"echo"}},
+		{"timeout 5s watch df", []string{"watch"}},
+		{"cat <file> | less", []string{"cat", "less"}}, // Doesn't parse
+	}
+	for _, tt := range tests {
+		if got := Commands(tt.command); !slices.Equal(got, tt.want) {
+			t.Errorf("Commands(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
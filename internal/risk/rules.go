@@ -0,0 +1,317 @@
+package risk
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// wrapper is a command that runs the command given in its arguments.
+// Wrappers are skipped so that "sudo rm -rf /" is judged as "rm -rf /".
+type wrapper struct {
+	valueOpts []string // Options that take the next argument as their value
+	operands  int      // Arguments before the command, like timeout's duration
+}
+
+var wrappers = map[string]wrapper{
+	"sudo": {valueOpts: []string{"-u", "-g", "-C", "-D", "-h", "-p", "-r", "-t", "-T", "-U",
+		"--user", "--group", "--close-from", "--chdir", "--host", "--prompt", "--role", "--type",
+		"--command-timeout", "--other-user"}},
+	"doas":    {valueOpts: []string{"-u", "-C"}},
+	"env":     {valueOpts: []string{"-u", "-C", "--unset", "--chdir"}},
+	"nice":    {valueOpts: []string{"-n", "--adjustment"}},
+	"ionice":  {valueOpts: []string{"-c", "-n", "--class", "--classdata"}},
+	"timeout": {valueOpts: []string{"-s", "-k", "--signal", "--kill-after"}, operands: 1},
+	"nohup":   {},
+	"time":    {valueOpts: []string{"-f", "-o", "--format", "--output"}},
+	"command": {},
+	"exec":    {valueOpts: []string{"-a"}},
+	"xargs": {valueOpts: []string{"-a", "-d", "-E", "-I", "-L", "-n", "-P", "-s",
+		"--arg-file", "--delimiter", "--max-lines", "--max-args", "--max-procs", "--max-chars",
+		"--process-slot-var"}},
+}
+
+// gitValueOpts are git's global options that take the next argument as
+// their value, given before the subcommand.
+var gitValueOpts = []string{"-C", "-c", "--git-dir", "--work-tree", "--namespace", "--config-env", "--exec-path"}
+
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "fish": true, "dash": true, "ksh": true,
+	"python": true, "python3": true, "perl": true, "ruby": true, "node": true,
+}
+
+var downloaders = map[string]bool{"curl": true, "wget": true, "fetch": true}
+
+var forkBombRe = regexp.MustCompile(`(\S+)\(\)\s*\{\s*(\S+)\s*\|\s*(\S+)\s*&\s*\}\s*;\s*(\S+)`)
+
+func forkBombPattern(command string) bool {
+	m := forkBombRe.FindStringSubmatch(command)
+	return m != nil && m[1] == m[2] && m[2] == m[3] && m[3] == m[4]
+}
+
+// unwrap strips leading wrappers (with their options, variable
+// assignments and operands) and returns the command they run.
+func unwrap(args []string) []string {
+	for len(args) > 0 {
+		w, ok := wrappers[path.Base(args[0])]
+		if !ok {
+			break
+		}
+		args = args[1:]
+		for {
+			args = skipOptions(args, w.valueOpts)
+			if len(args) == 0 || !strings.Contains(args[0], "=") {
+				break
+			}
+			args = args[1:]
+		}
+		args = args[min(w.operands, len(args)):]
+	}
+	return args
+}
+
+// skipOptions drops the options at the start of args, along with the
+// values of those in valueOpts, and a "--" ending them.
+func skipOptions(args, valueOpts []string) []string {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && args[0] != "-" {
+		opt := args[0]
+		args = args[1:]
+		if opt == "--" {
+			break
+		}
+		if slices.Contains(valueOpts, opt) && len(args) > 0 {
+			args = args[1:]
+		}
+	}
+	return args
+}
+
+// execCommands returns the commands find runs for each match with -exec,
+// -execdir, -ok and -okdir.
+func execCommands(args []string) [][]string {
+	var cmds [][]string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-exec", "-execdir", "-ok", "-okdir":
+			end := i + 1
+			for end < len(args) && args[end] != ";" && args[end] != `\;` && args[end] != "+" {
+				end++
+			}
+			cmds = append(cmds, args[i+1:end])
+			i = end
+		}
+	}
+	return cmds
+}
+
+// flags splits args into short flag letters, long flags and operands.
+func flags(args []string) (short string, long []string, operands []string) {
+	for _, a := range args {
+		switch {
+		case strings.HasPrefix(a, "--"):
+			long = append(long, a)
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+			short += a[1:]
+		default:
+			operands = append(operands, a)
+		}
+	}
+	return short, long, operands
+}
+
+func hasLong(long []string, names ...string) bool {
+	for _, l := range long {
+		for _, n := range names {
+			if l == n || strings.HasPrefix(l, n+"=") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// criticalPath reports whether deleting or rewriting p recursively would
+// wipe the system or the user's home.
+func criticalPath(p string) bool {
+	p = strings.TrimSuffix(strings.TrimSpace(p), "/*")
+	p = strings.TrimSuffix(p, "/")
+	switch p {
+	case "", "/", "~", "$HOME", "*", ".", "..", ".*", "$(...)":
+		return true
+	}
+	switch p {
+	case "/bin", "/boot", "/dev", "/etc", "/home", "/lib", "/lib64", "/opt",
+		"/root", "/sbin", "/srv", "/sys", "/usr", "/var", "/Users", "/System", "/Library":
+		return true
+	}
+	return false
+}
+
+func isDevice(p string) bool {
+	return strings.HasPrefix(p, "/dev/") && p != "/dev/null" && p != "/dev/zero" &&
+		p != "/dev/stdout" && p != "/dev/stderr" && p != "/dev/tty" && !strings.HasPrefix(p, "/dev/fd/")
+}
+
+// checkCall looks at a single simple command.
+func checkCall(a *Assessment, args []string) {
+	args = unwrap(args)
+	if len(args) == 0 {
+		return
+	}
+	name := path.Base(args[0])
+	if name == "git" {
+		// The subcommand comes after git's own options, as in git -C repo push
+		args = append(args[:1:1], skipOptions(args[1:], gitValueOpts)...)
+	}
+	short, long, operands := flags(args[1:])
+
+	switch {
+	case name == "rm":
+		recursive := strings.ContainsAny(short, "rR") || hasLong(long, "--recursive")
+		if hasLong(long, "--no-preserve-root") {
+			a.add(High, "rm --no-preserve-root can delete the whole filesystem")
+		}
+		if recursive {
+			level, target := Medium, ""
+			for _, op := range operands {
+				if criticalPath(op) {
+					level, target = High, op
+					break
+				}
+			}
+			if target != "" {
+				a.add(level, "recursive delete of "+target)
+			} else {
+				a.add(level, "recursive delete")
+			}
+		}
+
+	case name == "dd":
+		for _, op := range operands {
+			if of, ok := strings.CutPrefix(op, "of="); ok && isDevice(of) {
+				a.add(High, "dd writes raw data to "+of)
+			}
+		}
+
+	case strings.HasPrefix(name, "mkfs") || name == "wipefs" || name == "mkswap":
+		a.add(High, name+" erases a filesystem")
+	case name == "fdisk" || name == "sfdisk" || name == "parted" || name == "sgdisk" || name == "gdisk":
+		a.add(High, name+" changes the partition table")
+	case name == "shred":
+		for _, op := range operands {
+			if isDevice(op) {
+				a.add(High, "shred overwrites "+op)
+			}
+		}
+
+	case name == "chmod" || name == "chown" || name == "chgrp":
+		recursive := strings.Contains(short, "R") || hasLong(long, "--recursive")
+		if !recursive {
+			break
+		}
+		if name == "chmod" && len(operands) > 0 && worldWritable(operands[0]) {
+			a.add(High, "chmod -R "+operands[0]+" makes everything world-writable")
+		}
+		for _, op := range operands[min(1, len(operands)):] {
+			if criticalPath(op) {
+				a.add(High, name+" -R on "+op)
+			}
+		}
+
+	case name == "git" && len(operands) > 0 && operands[0] == "push":
+		if strings.Contains(short, "f") || hasLong(long, "--force") || hasLong(long, "--mirror") {
+			a.add(High, "force push overwrites remote history")
+		} else if hasLong(long, "--force-with-lease", "--force-if-includes") {
+			a.add(Medium, "force push (with lease) rewrites remote history")
+		}
+		for _, op := range operands[1:] {
+			if strings.HasPrefix(op, "+") {
+				a.add(High, "force push of "+strings.TrimPrefix(op, "+"))
+			}
+			if strings.HasPrefix(op, ":") && len(op) > 1 {
+				a.add(Medium, "deletes remote branch "+strings.TrimPrefix(op, ":"))
+			}
+		}
+		if hasLong(long, "--delete") {
+			a.add(Medium, "deletes a remote branch")
+		}
+	case name == "git" && len(operands) > 0 && operands[0] == "reset" && hasLong(long, "--hard"):
+		a.add(Medium, "git reset --hard discards uncommitted changes")
+	case name == "git" && len(operands) > 0 && operands[0] == "clean" && strings.Contains(short, "f"):
+		a.add(Medium, "git clean deletes untracked files")
+
+	case name == "find":
+		if slices.Contains(args, "-delete") {
+			a.add(Medium, "find -delete removes every match")
+		}
+		for _, cmd := range execCommands(args) {
+			checkCall(a, cmd)
+		}
+
+	case name == "kill" && len(args) > 1 && args[len(args)-1] == "-1":
+		a.add(High, "kill -1 signals every process you own")
+	case name == "shutdown" || name == "reboot" || name == "halt" || name == "poweroff":
+		a.add(Medium, name+" stops the machine")
+	}
+}
+
+func worldWritable(mode string) bool {
+	return mode == "777" || mode == "0777" || mode == "666" || mode == "0666" ||
+		strings.Contains(mode, "o+w") || strings.Contains(mode, "a+w") || mode == "a+rwx" || mode == "ugo+rwx"
+}
+
+// checkPipe flags downloads piped straight into an interpreter.
+func checkPipe(a *Assessment, left, right [][]string) {
+	downloads := false
+	for _, call := range left {
+		if args := unwrap(call); len(args) > 0 && downloaders[path.Base(args[0])] {
+			downloads = true
+		}
+	}
+	if !downloads {
+		return
+	}
+	for _, call := range right {
+		if args := unwrap(call); len(args) > 0 && shells[path.Base(args[0])] {
+			a.add(High, "pipes a download straight into "+path.Base(args[0]))
+		}
+	}
+}
+
+// checkShellSubst flags `bash <(curl ...)` and `sh -c "$(curl ...)"`.
+func checkShellSubst(a *Assessment, call *syntax.CallExpr) {
+	args := unwrap(words(call.Args))
+	if len(args) == 0 || !shells[path.Base(args[0])] {
+		return
+	}
+	syntax.Walk(call, func(node syntax.Node) bool {
+		var stmts []*syntax.Stmt
+		switch n := node.(type) {
+		case *syntax.CmdSubst:
+			stmts = n.Stmts
+		case *syntax.ProcSubst:
+			stmts = n.Stmts
+		}
+		for _, stmt := range stmts {
+			for _, inner := range stmtCalls(stmt) {
+				if innerArgs := unwrap(inner); len(innerArgs) > 0 && downloaders[path.Base(innerArgs[0])] {
+					a.add(High, "runs a downloaded script with "+path.Base(args[0]))
+				}
+			}
+		}
+		return true
+	})
+}
+
+// checkRedirect flags output redirected onto a block device.
+func checkRedirect(a *Assessment, op syntax.RedirOperator, target string) {
+	switch op {
+	case syntax.RdrOut, syntax.AppOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll:
+		if isDevice(target) {
+			a.add(High, "writes directly to "+target)
+		}
+	}
+}
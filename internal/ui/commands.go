@@ -0,0 +1,25 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+)
+
+var codeBlockRe = regexp.MustCompile("(?s)```(.*?)```")
+
+// ParseCommands returns the contents of the markdown code blocks in an
+// answer, with the language tag (```bash) dropped.
+func ParseCommands(answer string) []string {
+	var commands []string
+	for _, match := range codeBlockRe.FindAllStringSubmatch(answer, -1) {
+		raw := strings.TrimSpace(match[1])
+		if idx := strings.Index(raw, "\n"); idx != -1 {
+			firstLine := raw[:idx]
+			if !strings.Contains(firstLine, " ") {
+				raw = strings.TrimSpace(raw[idx+1:])
+			}
+		}
+		commands = append(commands, raw)
+	}
+	return commands
+}
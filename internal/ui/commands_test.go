@@ -0,0 +1,28 @@
+package ui
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseCommands(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer string
+		want   []string
+	}{
+		{"no block", "Just an answer.", nil},
+		{"language tag", "Run:\n```bash\nls -la\n```\n", []string{"ls -la"}},
+		{"no tag", "```\nls -la\n```", []string{"ls -la"}},
+		{"single line", "```df -h```", []string{"df -h"}},
+		{"first line is a command", "```\ncd /tmp\nls\n```", []string{"cd /tmp\nls"}},
+		{"several", "```sh\nmake\n```\nthen\n```sh\nmake install\n```", []string{"make", "make install"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseCommands(tt.answer); !slices.Equal(got, tt.want) {
+				t.Errorf("ParseCommands() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"huh/internal/history"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+)
+
+// historyRows is how many entries the history browser shows at once.
+const historyRows = 10
+
+// ShowHistory switches to the history browser, searching for query.
+func (m *Model) ShowHistory(entries []history.Entry, query string) {
+	m.HistoryEntries = entries
+	m.State = StateHistory
+	m.Input.SetValue(query)
+	m.Input.Placeholder = "search history"
+	m.Input.Focus()
+	m.filterHistory()
+}
+
+func (m Model) openHistory() (tea.Model, tea.Cmd) {
+	entries, err := m.LoadHistory()
+	if err != nil {
+		m.Err = fmt.Errorf("could not load history: %v", err)
+		m.State = StateError
+		return m, nil
+	}
+	// Whatever was typed so far becomes the search, like Ctrl-R in a shell
+	m.ShowHistory(entries, m.Input.Value())
+	return m, textinput.Blink
+}
+
+func (m *Model) filterHistory() {
+	m.HistoryMatches = history.Search(m.HistoryEntries, m.Input.Value())
+	if m.HistoryIndex >= len(m.HistoryMatches) {
+		m.HistoryIndex = max(len(m.HistoryMatches)-1, 0)
+	}
+}
+
+func (m Model) updateHistory(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "ctrl+p":
+		if m.HistoryIndex > 0 {
+			m.HistoryIndex--
+		}
+		return m, nil
+	case "down", "ctrl+n":
+		if m.HistoryIndex < len(m.HistoryMatches)-1 {
+			m.HistoryIndex++
+		}
+		return m, nil
+	case "enter":
+		if len(m.HistoryMatches) > 0 {
+			m.reopen(m.HistoryMatches[m.HistoryIndex])
+		}
+		return m, nil
+	case "ctrl+y":
+		if len(m.HistoryMatches) > 0 {
+			m.reopen(m.HistoryMatches[m.HistoryIndex])
+			for i, opt := range m.Options {
+				if opt == "Copy" {
+					m.SelectedOption = i
+				}
+			}
+			return m.handleSelection()
+		}
+		return m, nil
+	case "ctrl+r":
+		if len(m.HistoryMatches) > 0 {
+			e := m.HistoryMatches[m.HistoryIndex]
+			m.Question, m.Refinements = e.Question, nil
+			m.ContextInfo, m.ContextContent, m.RedactionPreview = "", "", nil
+			m.Input.Blur()
+			return m, m.send(queryRequest)
+		}
+		return m, nil
+	case "esc":
+		m.State = StateInput
+		m.Input.SetValue("")
+		m.Input.Placeholder = "e.g. how do I check disk space?"
+		return m, nil
+	case "ctrl+c":
+		return m, tea.Quit
+	}
+
+	var cmd tea.Cmd
+	m.Input, cmd = m.Input.Update(msg)
+	m.filterHistory()
+	return m, cmd
+}
+
+// reopen shows a past answer as if it had just arrived. Attached files
+// aren't stored, so the context starts out empty.
+func (m *Model) reopen(e history.Entry) {
+	m.Question, m.Refinements = e.Question, e.Refinements
+	m.ContextInfo, m.ContextContent, m.RedactionPreview = "", "", nil
+	m.AnsweredBy, m.AnsweredModel = e.Provider, e.Model
+	m.Cached = false
+	m.SelectedOption = 0
+	m.Input.Blur()
+	m.showSuggestion(e.Answer)
+	m.FromHistory = true
+	m.suggestionRequest = queryRequest // Asking again starts over
+}
+
+func (m Model) historyView() string {
+	var s strings.Builder
+	s.WriteString(TitleStyle.Render("History"))
+	s.WriteString("\n\n")
+	s.WriteString(m.Input.View())
+	s.WriteString("\n\n")
+
+	if len(m.HistoryMatches) == 0 {
+		if len(m.HistoryEntries) == 0 {
+			s.WriteString(ItemStyle.Render("Nothing here yet."))
+		} else {
+			s.WriteString(ItemStyle.Render("No matches."))
+		}
+		s.WriteString("\n\n(Esc to go back)")
+		return s.String()
+	}
+
+	start := 0
+	if m.HistoryIndex >= historyRows {
+		start = m.HistoryIndex - historyRows + 1
+	}
+	end := min(start+historyRows, len(m.HistoryMatches))
+
+	width := m.viewport.Width - 6
+	for i := start; i < end; i++ {
+		e := m.HistoryMatches[i]
+		line := fmt.Sprintf("%s  %s", e.Time.Local().Format("Jan 02 15:04"), strings.ReplaceAll(e.Question, "\n", " "))
+		for _, r := range e.Refinements {
+			line += " › " + strings.ReplaceAll(r, "\n", " ")
+		}
+		if len(e.Attachments) > 0 {
+			line += " (+" + strings.Join(e.Attachments, ", ") + ")"
+		}
+		if width > 0 {
+			line = truncate.StringWithTail(line, uint(width), "…")
+		}
+		if i == m.HistoryIndex {
+			s.WriteString(SelectedItemStyle.Render("> " + line))
+		} else {
+			s.WriteString(ItemStyle.Render("  " + line))
+		}
+		s.WriteString("\n")
+	}
+
+	// Preview of the selected entry
+	e := m.HistoryMatches[m.HistoryIndex]
+	s.WriteString("\n")
+	if len(e.Commands) > 0 {
+		s.WriteString(CommandStyle.Render(e.Commands[len(e.Commands)-1]))
+		s.WriteString("\n")
+	}
+	var details []string
+	if e.Provider != "" {
+		details = append(details, "via "+e.Provider)
+	}
+	if e.Model != "" {
+		details = append(details, e.Model)
+	}
+	if e.Action != "" {
+		details = append(details, e.Action)
+	}
+	if len(details) > 0 {
+		s.WriteString(lipgloss.NewStyle().Foreground(subtleColor).Render(strings.Join(details, " · ")))
+		s.WriteString("\n")
+	}
+
+	s.WriteString("\n(Type to search, Up/Down select, Enter open, Ctrl-Y copy, Ctrl-R ask again, Esc back)")
+	return s.String()
+}
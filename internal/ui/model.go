@@ -1,15 +1,22 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strings"
 
 	"time"
 
+	"huh/internal/attach"
+	"huh/internal/history"
+	"huh/internal/llm"
+	"huh/internal/risk"
+
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -25,12 +32,18 @@ const (
 	StateRefining
 	StateFilePrompt
 	StateLoading
+	StateStreaming
 	StateSuccessAnim
 	StateSuggestion
 	StateExplained
 	StateError
 	StatePermissionDenied
 	StateCopied
+	StateProviderPick
+	StateConfirmRun
+	StateRunResult
+	StateConfirmRisk
+	StateHistory
 )
 
 type CommandLayout struct {
@@ -42,14 +55,20 @@ type Model struct {
 	State              State
 	PreviousState      State // To return after file prompt
 	Question           string
+	Refinements        []string // Follow-up changes asked for after Question, in order
 	Input              textinput.Model
 	ContextInfo        string // Display string (e.g. "Attached: foo.txt")
 	ContextContent     string // Actual content
 	PermissionPath     string // Path that failed permission check
 	Suggestion         string
-	PendingSuggestion  string   // Holds suggestion during success animation
-	RunnableCommands   []string // Extracted commands for execution/copy
-	ActiveCommandIndex int      // Which command is currently selected
+	PendingSuggestion  string            // Holds suggestion while streaming and during success animation
+	RunnableCommands   []string          // Extracted commands for execution/copy
+	CommandRisks       []risk.Assessment // Risk of each entry in RunnableCommands
+	ActiveCommandIndex int               // Which command is currently selected
+	PendingAction      string            // Action waiting for risk confirmation
+	AnsweredBy         string            // Provider that produced the suggestion
+	AnsweredModel      string            // Model that produced the suggestion
+	Action             string            // What was done with the suggestion: copy, insert or run
 	Explanation        string
 	Err                error
 
@@ -57,9 +76,65 @@ type Model struct {
 	AnimationFrame int
 
 	// Query
-	QueryFunc   func(string, string) (string, error)
-	ExplainFunc func(string, string) (string, error)
-	RefineFunc  func(string, string, string) (string, error)
+	QueryFunc   func(context.Context, string, string, func(string)) (string, error)
+	ExplainFunc func(context.Context, string, string) (string, error)
+	RefineFunc  func(context.Context, string, string, string, func(string)) (string, error)
+
+	// ProviderFunc reports which provider answered the last request.
+	// Optional, with fallback providers this can differ between requests.
+	ProviderFunc func() string
+	// ModelFunc reports the model behind ProviderFunc's provider. Optional.
+	ModelFunc func() string
+
+	// Response cache, both optional
+	CachedFunc        func() bool // Reports whether the last answer came from the cache
+	Regenerate        func()      // Makes the next request skip the cache
+	Rewind            func()      // Rolls the conversation back to before the suggestion. Optional, enables asking another provider
+	Cached            bool
+	suggestionRequest func(Model) tea.Cmd // The request that produced the suggestion
+
+	// RedactedFunc lists the secrets masked in the last request. Optional.
+	RedactedFunc func() []string
+	Redacted     []string
+	// PreviewRedactionFunc lists the secrets that would be masked in the
+	// attachments, shown before they are sent. Optional.
+	PreviewRedactionFunc func(string) []string
+	RedactionPreview     []string
+
+	// Error recovery
+	ErrorOptions   []string
+	ErrorOption    int
+	Providers      []string                // Configured provider names
+	SwitchProvider func(name string) error // Optional, enables "Switch provider"
+	ProviderIndex  int                     // Cursor in StateProviderPick
+	provider       int                     // Index in Providers of the provider in use
+	lastRequest    func(Model) tea.Cmd     // Re-sends the last request, for "Retry"
+
+	// Run
+	Shell      string // Shell used for "Run", e.g. "zsh"
+	LastRun    RunDoneMsg
+	RunOptions []string
+	RunOption  int
+
+	// AttachmentBudget is how many bytes a file attached in the TUI may
+	// take before it is reduced. 0 means no limit.
+	AttachmentBudget int
+
+	// OutputPath, if set, receives the chosen command instead of the
+	// clipboard. Used by the shell integration to fill the prompt.
+	OutputPath string
+
+	// History
+	LoadHistory    func() ([]history.Entry, error) // Optional, enables Ctrl-R
+	HistoryEntries []history.Entry
+	HistoryMatches []history.Entry // HistoryEntries filtered by the search
+	HistoryIndex   int
+	FromHistory    bool // The suggestion was re-opened from history
+
+	// Streaming
+	stream chan tea.Msg
+	ctx    context.Context    // Of the request in flight
+	cancel context.CancelFunc // Abandons the request in flight
 
 	// Menu
 	Options        []string
@@ -81,7 +156,9 @@ type Model struct {
 	ready     bool
 }
 
-func NewModel(question string, contextInfo string, contextContent string, queryFunc func(string, string) (string, error), explainFunc func(string, string) (string, error), refineFunc func(string, string, string) (string, error)) Model {
+var suggestionOptions = []string{"Copy", "Run", "Explain", "Refine", "Cancel"}
+
+func NewModel(question string, contextInfo string, contextContent string, queryFunc func(context.Context, string, string, func(string)) (string, error), explainFunc func(context.Context, string, string) (string, error), refineFunc func(context.Context, string, string, string, func(string)) (string, error)) Model {
 	initialState := StateLoading
 	ti := textinput.New()
 	ti.Width = 50
@@ -92,41 +169,159 @@ func NewModel(question string, contextInfo string, contextContent string, queryF
 		ti.Focus()
 	}
 
-	return Model{
+	m := Model{
 		State:          initialState,
 		Question:       question,
 		Input:          ti,
 		ContextInfo:    contextInfo,
 		ContextContent: contextContent,
-		Options:        []string{"Copy", "Explain", "Refine", "Cancel"},
+		Options:        suggestionOptions,
 		SelectedOption: 0,
 		QueryFunc:      queryFunc,
 		ExplainFunc:    explainFunc,
 		RefineFunc:     refineFunc,
 	}
+	if initialState == StateLoading {
+		m.lastRequest = queryRequest
+		m.begin() // Init can't keep the cancel func, so it's made up front
+	}
+	return m
 }
 
 func (m Model) Init() tea.Cmd {
 	var cmds []tea.Cmd
-	if m.State == StateInput {
+	if m.State == StateInput || m.State == StateHistory {
 		cmds = append(cmds, textinput.Blink)
 	}
 	// Always perform query if in loading state (initial state might be loading)
 	if m.State == StateLoading {
-		cmds = append(cmds,
-			func() tea.Msg {
-				res, err := m.QueryFunc(m.Question, m.ContextContent)
-				if err != nil {
-					return ErrorMsg(err)
-				}
-				return SuggestionMsg(res)
-			},
-			tick(),
-		)
+		cmds = append(cmds, m.startQuery(), tick())
 	}
 	return tea.Batch(cmds...)
 }
 
+// latestRequest is the last refinement, or the question if there is none.
+func (m Model) latestRequest() string {
+	if n := len(m.Refinements); n > 0 {
+		return m.Refinements[n-1]
+	}
+	return m.Question
+}
+
+// startOver drops the refinements of a suggestion reopened from history,
+// since asking again only sends the question.
+func (m *Model) startOver() {
+	if m.FromHistory {
+		m.Refinements = nil
+	}
+}
+
+// send starts a request and remembers it so it can be retried on failure.
+// A request still in flight is abandoned.
+func (m *Model) send(request func(Model) tea.Cmd) tea.Cmd {
+	m.begin()
+	m.lastRequest = request
+	m.ErrorOptions = nil
+	m.State = StateLoading
+	return tea.Batch(request(*m), tick())
+}
+
+// begin gives the next request a context of its own, cancelling the
+// previous one's.
+func (m *Model) begin() {
+	m.abandon()
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+}
+
+// abandon cancels the request in flight, if any, so its goroutine and HTTP
+// request don't outlive it.
+func (m *Model) abandon() {
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+}
+
+func queryRequest(m Model) tea.Cmd {
+	return m.startQuery()
+}
+
+// startQuery runs QueryFunc for the current question and context.
+func (m Model) startQuery() tea.Cmd {
+	question, contextContent := m.Question, m.ContextContent
+	return startStream(m.ctx, func(ctx context.Context, onChunk func(string)) (string, error) {
+		return m.QueryFunc(ctx, question, contextContent, onChunk)
+	})
+}
+
+// startStream runs fn in the background and forwards every chunk it
+// produces as a StreamChunkMsg, followed by a SuggestionMsg or ErrorMsg.
+// Once ctx is cancelled nobody reads them any more, so fn is cancelled
+// and the rest is dropped.
+func startStream(ctx context.Context, fn func(ctx context.Context, onChunk func(string)) (string, error)) tea.Cmd {
+	return func() tea.Msg {
+		ch := make(chan tea.Msg)
+		go func() {
+			defer close(ch)
+			send := func(msg tea.Msg) {
+				select {
+				case ch <- msg:
+				case <-ctx.Done():
+				}
+			}
+			res, err := fn(ctx, func(chunk string) {
+				send(StreamChunkMsg(chunk))
+			})
+			if err != nil {
+				send(ErrorMsg(err))
+				return
+			}
+			send(SuggestionMsg(res))
+		}()
+		return StreamStartMsg(ch)
+	}
+}
+
+func waitForStream(ch chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// ReviewRedaction shows what will be masked in the attachments. A question
+// given up front isn't sent until the user confirms it with Enter.
+func (m *Model) ReviewRedaction() {
+	m.updateRedactionPreview()
+	if len(m.RedactionPreview) == 0 || m.State != StateLoading {
+		return
+	}
+	m.State = StateInput
+	m.Input.SetValue(m.Question)
+	m.Input.CursorEnd()
+	m.Input.Focus()
+	m.lastRequest = nil
+}
+
+func (m *Model) updateRedactionPreview() {
+	m.RedactionPreview = nil
+	if m.PreviewRedactionFunc != nil && m.ContextContent != "" {
+		m.RedactionPreview = m.PreviewRedactionFunc(m.ContextContent)
+	}
+}
+
+// redactionNotice lists what will be masked, for the views that send.
+func (m Model) redactionNotice() string {
+	if len(m.RedactionPreview) == 0 {
+		return ""
+	}
+	notice := "Will be redacted before sending: " + strings.Join(m.RedactionPreview, ", ")
+	return "\n" + lipgloss.NewStyle().Foreground(warningColor).Render(wordwrap.String(notice, m.viewport.Width))
+}
+
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var (
 		cmd  tea.Cmd
@@ -164,43 +359,39 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case CopiedTimeoutMsg:
 		return m, tea.Quit
 
+	case StreamStartMsg:
+		m.stream = msg
+		m.PendingSuggestion = ""
+		return m, waitForStream(m.stream)
+
+	case StreamChunkMsg:
+		m.PendingSuggestion += string(msg)
+		m.State = StateStreaming
+		return m, waitForStream(m.stream)
+
 	case SuggestionMsg:
 		// Transition to Success Animation
+		m.stream = nil
 		m.PendingSuggestion = string(msg)
+		if m.ProviderFunc != nil {
+			m.AnsweredBy = m.ProviderFunc()
+		}
+		if m.ModelFunc != nil {
+			m.AnsweredModel = m.ModelFunc()
+		}
+		m.Cached = m.CachedFunc != nil && m.CachedFunc()
+		m.Redacted = nil
+		if m.RedactedFunc != nil {
+			m.Redacted = m.RedactedFunc()
+		}
+		m.suggestionRequest = m.lastRequest
+		m.FromHistory = false
 		m.State = StateSuccessAnim
 		return m, waitForSuccess()
 
 	case SuccessTimeoutMsg:
-		m.Suggestion = m.PendingSuggestion
+		m.showSuggestion(m.PendingSuggestion)
 		m.PendingSuggestion = ""
-		m.Explanation = "" // Clear previous if any
-		m.State = StateSuggestion
-
-		// Parse Markdown Code Blocks
-		// Use regex to find all code blocks
-		re := regexp.MustCompile("(?s)```(.*?)```")
-		matches := re.FindAllStringSubmatch(m.Suggestion, -1)
-
-		m.RunnableCommands = nil
-		if len(matches) > 0 {
-			for _, match := range matches {
-				raw := match[1]
-				// Clean content
-				raw = strings.TrimSpace(raw)
-				if idx := strings.Index(raw, "\n"); idx != -1 {
-					firstLine := raw[:idx]
-					if !strings.Contains(firstLine, " ") {
-						raw = strings.TrimSpace(raw[idx+1:])
-					}
-				}
-				m.RunnableCommands = append(m.RunnableCommands, raw)
-			}
-			// Default to last command as active
-			m.ActiveCommandIndex = len(m.RunnableCommands) - 1
-		} else {
-			m.ActiveCommandIndex = -1
-		}
-		m.updateViewportContent()
 
 	case ExplanationMsg:
 		m.Explanation = string(msg)
@@ -208,9 +399,32 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.updateViewportContent()
 
 	case ErrorMsg:
+		m.stream = nil
 		m.Err = msg
 		m.State = StateError
+		m.ErrorOptions = m.recoveryOptions(msg)
+		m.ErrorOption = 0
 		return m, nil
+
+	case RunDoneMsg:
+		m.LastRun = msg
+		m.State = StateRunResult
+		m.RunOptions = []string{"Back", "Quit"}
+		if msg.ExitCode != 0 {
+			m.RunOptions = []string{"Ask about this output", "Back", "Quit"}
+		}
+		m.RunOption = 0
+		return m, nil
+
+	case PullDoneMsg:
+		if msg.Err != nil {
+			m.Err = fmt.Errorf("ollama pull failed: %v", msg.Err)
+			m.ErrorOptions = []string{"Quit"}
+			m.ErrorOption = 0
+			m.State = StateError
+			return m, nil
+		}
+		return m, m.send(m.lastRequest)
 	case tea.KeyMsg:
 		switch m.State {
 		case StateInput:
@@ -236,18 +450,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 
 				m.Question = m.Input.Value()
+				m.Refinements = nil
 				if m.Question != "" {
-					m.State = StateLoading
-					return m, tea.Batch(
-						func() tea.Msg {
-							res, err := m.QueryFunc(m.Question, m.ContextContent)
-							if err != nil {
-								return ErrorMsg(err)
-							}
-							return SuggestionMsg(res)
-						},
-						tick(),
-					)
+					return m, m.send(queryRequest)
+				}
+			case "ctrl+r":
+				if m.LoadHistory != nil {
+					return m.openHistory()
 				}
 			case "ctrl+c", "esc":
 				return m, tea.Quit
@@ -281,7 +490,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Submit Input
 				refinement := m.Input.Value()
 				if refinement != "" {
-					m.Question = refinement // Update question to reflect new query in UI
+					m.Refinements = append(slices.Clip(m.Refinements), refinement)
 
 					// Capture current suggestion for refinement logic
 					currentSuggestion := m.Suggestion
@@ -291,17 +500,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					// Clear suggestion in model so View() shows "Thinking about..." instead of "Explaining..."
 					m.Suggestion = ""
 
-					m.State = StateLoading
-					return m, tea.Batch(
-						func() tea.Msg {
-							res, err := m.RefineFunc(currentSuggestion, refinement, m.ContextContent)
-							if err != nil {
-								return ErrorMsg(err)
-							}
-							return SuggestionMsg(res)
-						},
-						tick(),
-					)
+					return m, m.send(func(m Model) tea.Cmd {
+						return startStream(m.ctx, func(ctx context.Context, onChunk func(string)) (string, error) {
+							return m.RefineFunc(ctx, currentSuggestion, refinement, m.ContextContent, onChunk)
+						})
+					})
 				}
 			case "esc":
 				m.State = StateSuggestion
@@ -375,7 +578,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 
 					// Read file
-					b, err := os.ReadFile(path)
+					content, stats, err := m.readAttachment(path)
 					if err != nil {
 						if os.IsPermission(err) {
 							m.PermissionPath = path
@@ -387,13 +590,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						return m, nil
 					}
 
-					// Append context
-					m.ContextContent += fmt.Sprintf("\n--- File: %s ---\n%s\n", path, string(b))
-					if m.ContextInfo == "" {
-						m.ContextInfo = path
-					} else {
-						m.ContextInfo += ", " + path
-					}
+					m.addAttachment(path, content, stats)
 
 					// Return to previous state
 					m.State = m.PreviousState
@@ -470,11 +667,24 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.viewport.ScrollUp(m.viewport.Height / 2)
 			case "pgdown", "ctrl+d":
 				m.viewport.ScrollDown(m.viewport.Height / 2)
+			case "p":
+				// Ask the same question somewhere else
+				if m.canReask() {
+					m.PreviousState = m.State
+					m.State = StateProviderPick
+					m.ProviderIndex = (m.provider + 1) % len(m.Providers)
+				}
+				return m, nil
 			case "enter":
 				// If at bottom and scrolling down, maybe Enter on simple text does nothing?
 				// But Enter triggers selection.
 				return m.handleSelection()
 			}
+		case StateLoading, StateStreaming:
+			if msg.String() == "ctrl+c" {
+				m.abandon()
+				return m, tea.Quit
+			}
 		case StateExplained:
 			switch msg.String() {
 			case "esc", "q":
@@ -489,7 +699,95 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.viewport.ScrollDown(m.viewport.Height / 2)
 			}
 		case StateError:
-			if msg.String() == "q" || msg.String() == "ctrl+c" {
+			switch msg.String() {
+			case "q", "ctrl+c":
+				return m, tea.Quit
+			case "left", "h":
+				if m.ErrorOption > 0 {
+					m.ErrorOption--
+				}
+			case "right", "l":
+				if m.ErrorOption < len(m.ErrorOptions)-1 {
+					m.ErrorOption++
+				}
+			case "enter":
+				return m.handleErrorOption()
+			}
+
+		case StateConfirmRisk:
+			switch msg.String() {
+			case "y", "Y":
+				// handleSelection sees PendingAction and skips the risk check
+				m.State = StateSuggestion
+				return m.handleSelection()
+			case "n", "N", "esc":
+				m.PendingAction = ""
+				m.State = StateSuggestion
+			case "ctrl+c":
+				return m, tea.Quit
+			}
+
+		case StateConfirmRun:
+			switch msg.String() {
+			case "y", "Y":
+				m.Action = "run"
+				return m, runCommand(m.Shell, m.RunnableCommands[m.ActiveCommandIndex])
+			case "n", "N", "esc":
+				m.State = StateSuggestion
+			case "ctrl+c":
+				return m, tea.Quit
+			}
+
+		case StateRunResult:
+			switch msg.String() {
+			case "q", "ctrl+c":
+				return m, tea.Quit
+			case "esc":
+				m.State = StateSuggestion
+			case "left", "h":
+				if m.RunOption > 0 {
+					m.RunOption--
+				}
+			case "right", "l":
+				if m.RunOption < len(m.RunOptions)-1 {
+					m.RunOption++
+				}
+			case "enter":
+				return m.handleRunOption()
+			}
+
+		case StateHistory:
+			return m.updateHistory(msg)
+
+		case StateProviderPick:
+			switch msg.String() {
+			case "up", "k":
+				if m.ProviderIndex > 0 {
+					m.ProviderIndex--
+				}
+			case "down", "j":
+				if m.ProviderIndex < len(m.Providers)-1 {
+					m.ProviderIndex++
+				}
+			case "enter":
+				if err := m.SwitchProvider(m.Providers[m.ProviderIndex]); err != nil {
+					m.Err = err
+					m.ErrorOptions = m.recoveryOptions(err)
+					m.ErrorOption = 0
+					m.State = StateError
+					return m, nil
+				}
+				m.provider = m.ProviderIndex
+				if m.PreviousState == StateSuggestion {
+					m.Rewind()
+					m.SelectedOption = 0
+					m.startOver()
+					return m, m.send(m.suggestionRequest)
+				}
+				return m, m.send(m.lastRequest)
+			case "esc":
+				m.State = m.PreviousState
+			case "ctrl+c":
 				return m, tea.Quit
 			}
 
@@ -517,7 +815,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		// Read temp file
-		b, err := os.ReadFile(msg.ContentPath)
+		content, stats, err := m.readAttachment(msg.ContentPath)
 		// Clean up
 		os.Remove(msg.ContentPath)
 
@@ -528,12 +826,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		// Success - append content
-		m.ContextContent += fmt.Sprintf("\n--- File: %s ---\n%s\n", m.PermissionPath, string(b))
-		if m.ContextInfo == "" {
-			m.ContextInfo = m.PermissionPath
-		} else {
-			m.ContextInfo += ", " + m.PermissionPath
-		}
+		m.addAttachment(m.PermissionPath, content, stats)
 
 		// Return to previous state
 		m.State = m.PreviousState
@@ -558,8 +851,56 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// showSuggestion displays answer and picks out its commands.
+func (m *Model) showSuggestion(answer string) {
+	m.Suggestion = answer
+	m.Explanation = "" // Clear previous if any
+	m.Action = ""
+	m.State = StateSuggestion
+
+	// Cached answers can be asked for again, fresh from the provider
+	m.Options = suggestionOptions
+	if m.Cached && m.Regenerate != nil {
+		m.Options = []string{"Copy", "Run", "Explain", "Refine", "Regenerate", "Cancel"}
+	}
+	if m.SelectedOption >= len(m.Options) {
+		m.SelectedOption = 0
+	}
+
+	m.RunnableCommands = ParseCommands(m.Suggestion)
+	if len(m.RunnableCommands) > 0 {
+		// Default to last command as active
+		m.ActiveCommandIndex = len(m.RunnableCommands) - 1
+	} else {
+		m.ActiveCommandIndex = -1
+	}
+	m.CommandRisks = nil
+	for _, c := range m.RunnableCommands {
+		m.CommandRisks = append(m.CommandRisks, risk.Analyze(c))
+	}
+	m.updateViewportContent()
+}
+
+// optionLabel is the text shown for a menu option. With OutputPath set
+// "Copy" puts the command on the prompt, so it says so.
+func (m Model) optionLabel(opt string) string {
+	if opt == "Copy" && m.OutputPath != "" {
+		return "Insert"
+	}
+	return opt
+}
+
 func (m Model) handleSelection() (tea.Model, tea.Cmd) {
 	selected := m.Options[m.SelectedOption]
+
+	// High risk commands need an extra keystroke before copy or run
+	if (selected == "Copy" || selected == "Run") && m.PendingAction == "" && m.activeRisk().Level == risk.High {
+		m.PendingAction = selected
+		m.State = StateConfirmRisk
+		return m, nil
+	}
+	m.PendingAction = ""
+
 	switch selected {
 	case "Copy":
 		if len(m.RunnableCommands) == 0 {
@@ -569,26 +910,46 @@ func (m Model) handleSelection() (tea.Model, tea.Cmd) {
 		}
 		// Copy Active Command
 		cmd := m.RunnableCommands[m.ActiveCommandIndex]
+		if m.OutputPath != "" {
+			if err := os.WriteFile(m.OutputPath, []byte(cmd), 0600); err != nil {
+				m.Err = fmt.Errorf("failed to write command: %v", err)
+				m.State = StateError
+				return m, nil
+			}
+			m.Action = "insert"
+			return m, tea.Quit
+		}
 		if err := clipboard.WriteAll(cmd); err != nil {
 			m.Err = fmt.Errorf("failed to copy: %v (install wl-clipboard or xclip)", err)
 			m.State = StateError
 			return m, nil
 		}
+		m.Action = "copy"
 		m.State = StateCopied
 		return m, waitForCopy()
+	case "Run":
+		if len(m.RunnableCommands) == 0 {
+			m.Err = fmt.Errorf("no executable command found to run")
+			m.State = StateError
+			return m, nil
+		}
+		m.State = StateConfirmRun
+		return m, nil
 	case "Explain":
-		m.State = StateLoading // Show loading while explaining
-		return m, func() tea.Msg {
-			target := m.Suggestion
-			if len(m.RunnableCommands) > 0 {
-				target = m.RunnableCommands[m.ActiveCommandIndex]
-			}
-			exp, err := m.ExplainFunc(target, m.ContextContent)
-			if err != nil {
-				return ErrorMsg(err)
-			}
-			return ExplanationMsg(exp)
+		target := m.Suggestion
+		if len(m.RunnableCommands) > 0 {
+			target = m.RunnableCommands[m.ActiveCommandIndex]
 		}
+		// Show loading while explaining
+		return m, m.send(func(m Model) tea.Cmd {
+			return func() tea.Msg {
+				exp, err := m.ExplainFunc(m.ctx, target, m.ContextContent)
+				if err != nil {
+					return ErrorMsg(err)
+				}
+				return ExplanationMsg(exp)
+			}
+		})
 	case "Refine":
 		if len(m.RunnableCommands) == 0 {
 			m.Err = fmt.Errorf("no command to edit")
@@ -601,12 +962,151 @@ func (m Model) handleSelection() (tea.Model, tea.Cmd) {
 		m.Input.Focus()
 		return m, textinput.Blink
 
+	case "Regenerate":
+		m.Regenerate()
+		m.SelectedOption = 0
+		m.startOver()
+		return m, m.send(m.suggestionRequest)
+
 	case "Cancel":
 		return m, tea.Quit
 	}
 	return m, nil
 }
 
+func (m Model) handleRunOption() (tea.Model, tea.Cmd) {
+	switch m.RunOptions[m.RunOption] {
+	case "Ask about this output":
+		// Attach the output and continue the conversation as a follow-up
+		run := m.LastRun
+		output := run.Output
+		if run.Interactive {
+			output = "(not captured, the command used the terminal directly)\n"
+		}
+		if run.Err != nil {
+			output += run.Err.Error()
+		}
+		m.ContextContent += fmt.Sprintf("\n--- Output of: %s (exit code %d) ---\n%s\n", run.Command, run.ExitCode, output)
+		if m.ContextInfo == "" {
+			m.ContextInfo = "Command output"
+		} else if !strings.Contains(m.ContextInfo, "Command output") {
+			m.ContextInfo += ", Command output"
+		}
+		m.updateRedactionPreview()
+
+		m.State = StateRefining
+		m.FocusIndex = 0
+		m.Input.Placeholder = "Your follow-up question here..."
+		m.Input.SetValue(fmt.Sprintf("It failed with exit code %d. Why, and how do I fix it?", run.ExitCode))
+		m.Input.CursorEnd()
+		m.Input.Focus()
+		return m, textinput.Blink
+	case "Back":
+		m.State = StateSuggestion
+		return m, nil
+	case "Quit":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// activeRisk returns the assessment of the selected command.
+func (m Model) activeRisk() risk.Assessment {
+	if m.ActiveCommandIndex < 0 || m.ActiveCommandIndex >= len(m.CommandRisks) {
+		return risk.Assessment{}
+	}
+	return m.CommandRisks[m.ActiveCommandIndex]
+}
+
+// riskBadge renders the risk level and reasons of a command, or "" for
+// commands that look harmless.
+func (m Model) riskBadge(index int) string {
+	if index < 0 || index >= len(m.CommandRisks) {
+		return ""
+	}
+	assessment := m.CommandRisks[index]
+	var label string
+	style := MediumRiskStyle
+	switch assessment.Level {
+	case risk.High:
+		label, style = "⚠ HIGH RISK", HighRiskStyle
+	case risk.Medium:
+		label = "⚠ Caution"
+	default:
+		return ""
+	}
+	return wordwrap.String(style.Render(label+": "+strings.Join(assessment.Reasons(), "; ")), m.viewport.Width)
+}
+
+// recoveryOptions lists what the user can do about a failed request,
+// depending on what went wrong.
+func (m Model) recoveryOptions(err error) []string {
+	var opts []string
+	canSwitch := m.SwitchProvider != nil && len(m.Providers) > 1
+
+	switch {
+	case errors.Is(err, llm.ErrModelNotFound):
+		if _, ok := pullableModel(err); ok {
+			opts = append(opts, "Pull model")
+		}
+	case errors.Is(err, llm.ErrContextTooLong):
+		if _, ok := trimAttachments(m.ContextContent); ok {
+			opts = append(opts, "Trim attachments")
+		}
+	case errors.Is(err, llm.ErrAuth):
+		// Retrying won't help until the key is fixed
+	default:
+		if m.lastRequest != nil {
+			opts = append(opts, "Retry")
+		}
+	}
+	if canSwitch {
+		opts = append(opts, "Switch provider")
+	}
+	return append(opts, "Quit")
+}
+
+// canReask reports whether the suggestion can be asked for again from
+// another provider.
+func (m Model) canReask() bool {
+	return m.SwitchProvider != nil && m.Rewind != nil && m.suggestionRequest != nil && len(m.Providers) > 1
+}
+
+func (m Model) handleErrorOption() (tea.Model, tea.Cmd) {
+	if len(m.ErrorOptions) == 0 {
+		return m, nil
+	}
+	switch m.ErrorOptions[m.ErrorOption] {
+	case "Retry":
+		return m, m.send(m.lastRequest)
+	case "Switch provider":
+		m.PreviousState = m.State
+		m.State = StateProviderPick
+		m.ProviderIndex = 0
+		return m, nil
+	case "Pull model":
+		model, _ := pullableModel(m.Err)
+		return m, pullModel(model)
+	case "Trim attachments":
+		trimmed, ok := trimAttachments(m.ContextContent)
+		if !ok {
+			m.Err = fmt.Errorf("%w, and the attachments can't be trimmed further", m.Err)
+			m.ErrorOptions = m.recoveryOptions(m.Err)
+			m.ErrorOption = 0
+			return m, nil
+		}
+		m.ContextContent = trimmed
+		if !strings.HasSuffix(m.ContextInfo, " (trimmed)") {
+			m.ContextInfo += " (trimmed)"
+		}
+		m.updateRedactionPreview()
+		return m, m.send(m.lastRequest)
+	case "Quit":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
 func (m *Model) updateViewportContent() {
 	var content strings.Builder
 
@@ -636,6 +1136,9 @@ func (m *Model) updateViewportContent() {
 
 						// Render command
 						renderedCmd := style.Render(cmdVal)
+						if badge := m.riskBadge(cmdIndex); badge != "" {
+							renderedCmd += "\n" + badge
+						}
 						content.WriteString(renderedCmd)
 
 						// Record position
@@ -671,6 +1174,12 @@ func (m *Model) updateViewportContent() {
 			content.WriteString(wordwrap.String(lipgloss.NewStyle().Foreground(subtleColor).Render("(Tab to cycle commands)"), m.viewport.Width))
 			content.WriteString("\n")
 		}
+		// Listed last so the command positions above stay put
+		if len(m.Redacted) > 0 {
+			redacted := "Redacted before sending: " + strings.Join(m.Redacted, ", ")
+			content.WriteString(lipgloss.NewStyle().Foreground(subtleColor).Render(wordwrap.String(redacted, m.viewport.Width)))
+			content.WriteString("\n")
+		}
 		content.WriteString("\n")
 	} else if m.State == StateExplained {
 		content.WriteString(wordwrap.String(DescriptionStyle.Render(m.Explanation), m.viewport.Width))
@@ -727,6 +1236,7 @@ func (m Model) View() string {
 		if m.ContextInfo != "" {
 			s.WriteString(lipgloss.NewStyle().Foreground(subtleColor).Render(fmt.Sprintf("\n(Context: %s)", m.ContextInfo)))
 		}
+		s.WriteString(m.redactionNotice())
 		s.WriteString("\n\n")
 
 		// Input View
@@ -740,10 +1250,15 @@ func (m Model) View() string {
 		}
 		s.WriteString(btnStyle.Render("[ Attach File ]"))
 
-		s.WriteString("\n\n(Tab to select, Enter to confirm, Esc to quit)")
+		if m.LoadHistory != nil {
+			s.WriteString("\n\n(Tab to select, Enter to confirm, Ctrl-R history, Esc to quit)")
+		} else {
+			s.WriteString("\n\n(Tab to select, Enter to confirm, Esc to quit)")
+		}
 
 	case StateRefining:
 		s.WriteString(TitleStyle.Render("How should the command be changed?"))
+		s.WriteString(m.redactionNotice())
 		s.WriteString("\n\n")
 		s.WriteString(lipgloss.NewStyle().Foreground(secondaryColor).Render(m.Suggestion))
 		s.WriteString("\n\n")
@@ -857,7 +1372,7 @@ func (m Model) View() string {
 		robot := fmt.Sprintf("%s\n%s\n      %s\n%s", antenna, top, eyes, bot)
 
 		if m.Explanation == "" && m.Suggestion == "" {
-			s.WriteString(fmt.Sprintf("Thinking about: %s...", m.Question))
+			s.WriteString(fmt.Sprintf("Thinking about: %s...", m.latestRequest()))
 			if m.ContextInfo != "" {
 				s.WriteString(fmt.Sprintf("\n(Context: %s)", m.ContextInfo))
 			}
@@ -868,6 +1383,18 @@ func (m Model) View() string {
 			s.WriteString(robot)
 		}
 
+	case StateStreaming:
+		s.WriteString(TitleStyle.Render("Suggestion:"))
+		s.WriteString("\n")
+
+		// Show the tail of the partial answer so the newest text stays on screen
+		partial := wordwrap.String(m.PendingSuggestion, m.viewport.Width) + lipgloss.NewStyle().Foreground(primaryColor).Render("▍")
+		lines := strings.Split(partial, "\n")
+		if m.maxHeight > 0 && len(lines) > m.maxHeight {
+			lines = lines[len(lines)-m.maxHeight:]
+		}
+		s.WriteString(strings.Join(lines, "\n"))
+
 	case StateSuccessAnim:
 		// Success Robot
 		eyeStyle := lipgloss.NewStyle().Foreground(secondaryColor).Bold(true)
@@ -880,11 +1407,20 @@ func (m Model) View() string {
 
 		robot := fmt.Sprintf("%s\n%s \n       %s\n%s", sparkles, top, eyes, bot)
 
-		s.WriteString(fmt.Sprintf("Thinking about: %s...\n", m.Question))
+		s.WriteString(fmt.Sprintf("Thinking about: %s...\n", m.latestRequest()))
 		s.WriteString(robot)
 
 	case StateSuggestion:
 		s.WriteString(TitleStyle.Render("Suggestion:"))
+		if m.AnsweredBy != "" {
+			s.WriteString(lipgloss.NewStyle().Foreground(subtleColor).Render(" via " + m.AnsweredBy))
+		}
+		if m.Cached {
+			s.WriteString(lipgloss.NewStyle().Foreground(subtleColor).Render(" (cached)"))
+		}
+		if n := len(m.Redacted); n > 0 {
+			s.WriteString(lipgloss.NewStyle().Foreground(warningColor).Render(fmt.Sprintf(" (%d redacted)", n)))
+		}
 		s.WriteString("\n")
 
 		s.WriteString(m.viewport.View())
@@ -912,10 +1448,14 @@ func (m Model) View() string {
 			if m.SelectedOption == i {
 				style = SelectedItemStyle
 			}
-			options = append(options, style.Render(opt))
+			options = append(options, style.Render(m.optionLabel(opt)))
 		}
 		s.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, options...))
-		s.WriteString(lipgloss.NewStyle().Foreground(subtleColor).Render("  (<-/-> select, Enter confirm, Arrows scroll)"))
+		hint := "  (<-/-> select, Enter confirm, Arrows scroll)"
+		if m.canReask() {
+			hint = "  (<-/-> select, Enter confirm, Arrows scroll, p ask another provider)"
+		}
+		s.WriteString(lipgloss.NewStyle().Foreground(subtleColor).Render(hint))
 
 	case StateExplained:
 		s.WriteString(TitleStyle.Render("Explanation:"))
@@ -927,7 +1467,115 @@ func (m Model) View() string {
 		s.WriteString(TitleStyle.Foreground(errorColor).Render("Error:"))
 		s.WriteString("\n")
 		s.WriteString(fmt.Sprintf("%v", m.Err))
-		s.WriteString("\n\n(Press q to quit)")
+		if hint := ErrorHint(m.Err); hint != "" {
+			s.WriteString("\n")
+			s.WriteString(DescriptionStyle.Render(hint))
+		}
+
+		if len(m.ErrorOptions) == 0 {
+			s.WriteString("\n\n(Press q to quit)")
+			break
+		}
+		s.WriteString("\n\n")
+		var options []string
+		for i, opt := range m.ErrorOptions {
+			style := ItemStyle
+			if m.ErrorOption == i {
+				style = SelectedItemStyle
+			}
+			options = append(options, style.Render(opt))
+		}
+		s.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, options...))
+		s.WriteString(lipgloss.NewStyle().Foreground(subtleColor).Render("  (<-/-> select, Enter confirm, q quit)"))
+
+	case StateConfirmRisk:
+		s.WriteString(HighRiskStyle.Render("⚠ This command looks dangerous"))
+		s.WriteString("\n\n")
+		s.WriteString(CommandStyle.Copy().BorderForeground(errorColor).Render(m.RunnableCommands[m.ActiveCommandIndex]))
+		s.WriteString("\n")
+		for _, reason := range m.activeRisk().Reasons() {
+			s.WriteString(HighRiskStyle.Render("  • " + reason))
+			s.WriteString("\n")
+		}
+		s.WriteString(fmt.Sprintf("\n%s anyway? (y to continue, n to go back)", m.optionLabel(m.PendingAction)))
+
+	case StateConfirmRun:
+		shell := m.Shell
+		if shell == "" {
+			shell = "sh"
+		}
+		s.WriteString(TitleStyle.Render(fmt.Sprintf("Run this command in %s?", shell)))
+		s.WriteString("\n\n")
+		s.WriteString(CommandStyle.Render(m.RunnableCommands[m.ActiveCommandIndex]))
+		if badge := m.riskBadge(m.ActiveCommandIndex); badge != "" {
+			s.WriteString("\n" + badge)
+		}
+		s.WriteString("\n\n(y to run, n to go back)")
+
+	case StateRunResult:
+		run := m.LastRun
+		if run.ExitCode == 0 {
+			s.WriteString(TitleStyle.Copy().Foreground(secondaryColor).Render("✓ Command succeeded"))
+		} else {
+			s.WriteString(TitleStyle.Copy().Foreground(errorColor).Render(fmt.Sprintf("✗ Command failed (exit code %d)", run.ExitCode)))
+		}
+		s.WriteString("\n")
+		s.WriteString(InactiveCommandStyle.Render(run.Command))
+		s.WriteString("\n")
+		if run.Err != nil {
+			s.WriteString(fmt.Sprintf("%v\n", run.Err))
+		}
+
+		// Only the tail of the output, the full text is still on the terminal
+		output := strings.TrimRight(run.Output, "\n")
+		if output != "" {
+			lines := strings.Split(output, "\n")
+			if len(lines) > 10 {
+				lines = lines[len(lines)-10:]
+			}
+			s.WriteString(DescriptionStyle.Render(wordwrap.String(strings.Join(lines, "\n"), m.viewport.Width)))
+			s.WriteString("\n")
+		}
+		s.WriteString("\n")
+
+		var options []string
+		for i, opt := range m.RunOptions {
+			style := ItemStyle
+			if m.RunOption == i {
+				style = SelectedItemStyle
+			}
+			options = append(options, style.Render(opt))
+		}
+		s.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, options...))
+		s.WriteString(lipgloss.NewStyle().Foreground(subtleColor).Render("  (<-/-> select, Enter confirm)"))
+
+	case StateHistory:
+		s.WriteString(m.historyView())
+
+	case StateProviderPick:
+		reask := m.PreviousState == StateSuggestion
+		if reask {
+			s.WriteString(TitleStyle.Render("Ask another provider:"))
+		} else {
+			s.WriteString(TitleStyle.Render("Switch to provider:"))
+		}
+		s.WriteString("\n\n")
+		for i, name := range m.Providers {
+			if i == m.provider {
+				name += " (current)"
+			}
+			if i == m.ProviderIndex {
+				s.WriteString(SelectedItemStyle.Render("> " + name))
+			} else {
+				s.WriteString(ItemStyle.Render("  " + name))
+			}
+			s.WriteString("\n")
+		}
+		if reask {
+			s.WriteString("\n(Up/Down to select, Enter to ask again, Esc to go back)")
+		} else {
+			s.WriteString("\n(Up/Down to select, Enter to switch and retry, Esc to go back)")
+		}
 
 	case StatePermissionDenied:
 		s.WriteString(TitleStyle.Foreground(errorColor).Render("Permission Denied"))
@@ -950,12 +1598,18 @@ func SetSuggestion(cmd string) tea.Msg {
 }
 
 type SuggestionMsg string
+type StreamStartMsg chan tea.Msg
+type StreamChunkMsg string
 type ExplanationMsg string
 type ErrorMsg error
 type TickMsg time.Time
 type SuccessTimeoutMsg time.Time
 type CopiedTimeoutMsg time.Time
 
+type PullDoneMsg struct {
+	Err error
+}
+
 type SudoReadMsg struct {
 	Err         error
 	ContentPath string
@@ -1045,3 +1699,88 @@ func sudoRead(path string) tea.Cmd {
 		})()
 	}
 }
+
+// ErrorHint suggests what to do about a provider error, "" if nothing
+// specific helps.
+func ErrorHint(err error) string {
+	switch {
+	case errors.Is(err, llm.ErrAuth):
+		return "The provider rejected the credentials. Check the api_key in your config."
+	case errors.Is(err, llm.ErrModelNotFound):
+		return "The configured model isn't available on this provider."
+	case errors.Is(err, llm.ErrRateLimited):
+		var apiErr *llm.APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			return fmt.Sprintf("The provider is rate limiting requests. Try again in %s.", apiErr.RetryAfter.Round(time.Second))
+		}
+		return "The provider is rate limiting requests. Wait a moment and retry."
+	case errors.Is(err, llm.ErrContextTooLong):
+		return "The question and attachments don't fit in the model's context window."
+	case errors.Is(err, llm.ErrTimeout):
+		return "The provider took too long to answer. Retry, or raise the provider's timeout param."
+	case errors.Is(err, llm.ErrNetwork):
+		var reqErr *llm.RequestError
+		if errors.As(err, &reqErr) && reqErr.Type == "ollama" {
+			return "Could not reach Ollama. Is `ollama serve` running?"
+		}
+		return "Could not reach the provider. Check your connection."
+	}
+	return ""
+}
+
+// pullableModel returns the missing model if it can be pulled with the
+// local ollama binary.
+func pullableModel(err error) (*llm.APIError, bool) {
+	var apiErr *llm.APIError
+	if !errors.As(err, &apiErr) || apiErr.Type != "ollama" || apiErr.Model == "" {
+		return nil, false
+	}
+	if _, err := exec.LookPath("ollama"); err != nil {
+		return nil, false
+	}
+	return apiErr, true
+}
+
+// pullModel runs `ollama pull` in the foreground against the host that
+// reported the missing model.
+func pullModel(apiErr *llm.APIError) tea.Cmd {
+	c := exec.Command("ollama", "pull", apiErr.Model)
+	if apiErr.Endpoint != "" {
+		c.Env = append(os.Environ(), "OLLAMA_HOST="+apiErr.Endpoint)
+	}
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		return PullDoneMsg{Err: err}
+	})
+}
+
+// trimAttachments roughly halves the attached context, keeping its start,
+// its end and the error lines in between. Repeated calls keep shrinking it
+// until there's nothing left to cut, then ok is false and content is
+// returned as is.
+func trimAttachments(content string) (trimmed string, ok bool) {
+	trimmed, _ = attach.ReduceString(content, len(content)/2)
+	if trimmed == "" || len(trimmed) >= len(content) {
+		return content, false
+	}
+	return trimmed, true
+}
+
+// readAttachment reads the file at path, reduced to AttachmentBudget.
+func (m Model) readAttachment(path string) (string, attach.Stats, error) {
+	if m.AttachmentBudget <= 0 {
+		b, err := os.ReadFile(path)
+		return string(b), attach.Stats{}, err
+	}
+	return attach.ReadFile(path, m.AttachmentBudget)
+}
+
+// addAttachment appends a file to the context and names it in ContextInfo,
+// noting if it had to be trimmed.
+func (m *Model) addAttachment(path, content string, stats attach.Stats) {
+	m.ContextContent += fmt.Sprintf("\n--- File: %s ---\n%s\n", path, content)
+	if m.ContextInfo != "" {
+		m.ContextInfo += ", "
+	}
+	m.ContextInfo += attach.Describe(path, stats)
+	m.updateRedactionPreview()
+}
@@ -0,0 +1,231 @@
+package ui
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"huh/internal/attach"
+	"huh/internal/llm"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestReaskAnotherProvider(t *testing.T) {
+	query := func(context.Context, string, string, func(string)) (string, error) {
+		return "```bash\nls\n```", nil
+	}
+	m := NewModel("list files", "", "", query, nil, nil)
+	m.Providers = []string{"ollama", "openai", "anthropic"}
+	var switched string
+	m.SwitchProvider = func(name string) error {
+		switched = name
+		return nil
+	}
+	rewound := false
+	m.Rewind = func() { rewound = true }
+
+	// Answer the question
+	var model tea.Model = m
+	model, _ = model.Update(SuggestionMsg("```bash\nls\n```"))
+	model, _ = model.Update(SuccessTimeoutMsg{})
+	if got := model.(Model).State; got != StateSuggestion {
+		t.Fatalf("State = %v, want StateSuggestion", got)
+	}
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	m = model.(Model)
+	if m.State != StateProviderPick || m.ProviderIndex != 1 {
+		t.Fatalf("State = %v, ProviderIndex = %d, want the picker on the next provider", m.State, m.ProviderIndex)
+	}
+
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = model.(Model)
+	if switched != "openai" || !rewound {
+		t.Errorf("switched to %q, rewound %v", switched, rewound)
+	}
+	if m.State != StateLoading || cmd == nil {
+		t.Errorf("State = %v, want the question asked again", m.State)
+	}
+
+	// Esc from the picker goes back to the suggestion
+	m.State = StateSuggestion
+	model, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	if got := model.(Model).ProviderIndex; got != 2 {
+		t.Errorf("ProviderIndex = %d, want 2 after switching to openai", got)
+	}
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if got := model.(Model).State; got != StateSuggestion {
+		t.Errorf("State = %v after Esc, want StateSuggestion", got)
+	}
+}
+
+func TestReaskNeedsAnotherProvider(t *testing.T) {
+	m := NewModel("list files", "", "", nil, nil, nil)
+	m.Providers = []string{"ollama"}
+	m.SwitchProvider = func(string) error { return nil }
+	m.Rewind = func() {}
+	m.suggestionRequest = queryRequest
+	m.State = StateSuggestion
+
+	model, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	if got := model.(Model).State; got != StateSuggestion {
+		t.Errorf("State = %v, want StateSuggestion with a single provider", got)
+	}
+}
+
+func TestAbandonStream(t *testing.T) {
+	cancelled := make(chan struct{})
+	query := func(ctx context.Context, _, _ string, onChunk func(string)) (string, error) {
+		onChunk("ls")
+		<-ctx.Done()
+		close(cancelled)
+		onChunk(" -la") // Nobody reads this any more
+		return "", ctx.Err()
+	}
+	m := NewModel("list files", "", "", query, nil, nil)
+	var model tea.Model = m
+	model, cmd := model.Update(m.startQuery()())
+	model, _ = model.Update(cmd())
+	if got := model.(Model).PendingSuggestion; got != "ls" {
+		t.Fatalf("PendingSuggestion = %q, want the first chunk", got)
+	}
+
+	// Quitting mid-stream cancels the request and ends the goroutine
+	stream := model.(Model).stream
+	model.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the request wasn't cancelled on quit")
+	}
+	select {
+	case _, ok := <-stream:
+		if ok {
+			t.Error("the abandoned stream sent another message")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the stream's goroutine didn't finish")
+	}
+
+	// A new request abandons the one in flight
+	m = NewModel("list files", "", "", query, nil, nil)
+	ctx := m.ctx
+	m.send(queryRequest)
+	if ctx.Err() == nil {
+		t.Error("sending a new request didn't cancel the previous one")
+	}
+}
+
+func TestRefineKeepsQuestion(t *testing.T) {
+	refine := func(context.Context, string, string, string, func(string)) (string, error) {
+		return "```bash\nls -la\n```", nil
+	}
+	m := NewModel("list files", "", "", nil, nil, refine)
+	var model tea.Model = m
+	model, _ = model.Update(SuggestionMsg("```bash\nls\n```"))
+	model, _ = model.Update(SuccessTimeoutMsg{})
+
+	for _, r := range []string{"include hidden ones", "sorted by size"} {
+		m = model.(Model)
+		m.State = StateRefining
+		m.FocusIndex = 0
+		m.Input.SetValue(r)
+		var cmd tea.Cmd
+		model, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if cmd == nil {
+			t.Fatalf("refining with %q sent nothing", r)
+		}
+	}
+
+	m = model.(Model)
+	if m.Question != "list files" {
+		t.Errorf("Question = %q, want the original question", m.Question)
+	}
+	want := []string{"include hidden ones", "sorted by size"}
+	if strings.Join(m.Refinements, "|") != strings.Join(want, "|") {
+		t.Errorf("Refinements = %q, want %q", m.Refinements, want)
+	}
+}
+
+func TestTrimAttachments(t *testing.T) {
+	tooLong := &llm.APIError{Provider: "openai", StatusCode: 400, Kind: llm.ErrContextTooLong}
+	long := strings.Repeat("INFO all good\n", 200) + "ERROR disk full\n"
+
+	tests := []struct {
+		name     string
+		content  string
+		wantTrim bool
+	}{
+		{"long log", long, true},
+		{"short attachment", "--- File: a.txt ---\nhello\n", false},
+		{"one long line", "--- Stdin ---\n" + strings.Repeat("x", 100) + "\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewModel("why?", "a.txt", tt.content, nil, nil, nil)
+			model, _ := m.Update(ErrorMsg(tooLong))
+			m = model.(Model)
+			if got := slices.Contains(m.ErrorOptions, "Trim attachments"); got != tt.wantTrim {
+				t.Errorf("ErrorOptions = %q, want Trim attachments offered: %v", m.ErrorOptions, tt.wantTrim)
+			}
+
+			// Even when picked anyway, trimming never empties an attachment
+			m.ErrorOptions = []string{"Trim attachments"}
+			m.ErrorOption = 0
+			model, _ = m.handleErrorOption()
+			m = model.(Model)
+			if m.ContextContent == "" {
+				t.Fatal("trimming emptied the attachments")
+			}
+			if trimmed := len(m.ContextContent) < len(tt.content); trimmed != tt.wantTrim {
+				t.Errorf("trimmed to %d of %d bytes", len(m.ContextContent), len(tt.content))
+			}
+			if got := strings.HasSuffix(m.ContextInfo, "(trimmed)"); got != tt.wantTrim {
+				t.Errorf("ContextInfo = %q", m.ContextInfo)
+			}
+			if !tt.wantTrim && !strings.Contains(m.Err.Error(), "can't be trimmed further") {
+				t.Errorf("Err = %v, want it to say the attachments can't be trimmed", m.Err)
+			}
+		})
+	}
+}
+
+func TestReviewRedaction(t *testing.T) {
+	preview := func(content string) []string {
+		if strings.Contains(content, "hunter2") {
+			return []string{"password hu…r2"}
+		}
+		return nil
+	}
+
+	// Nothing to mask, the question is sent right away
+	m := NewModel("why?", "log.txt", "all fine", nil, nil, nil)
+	m.PreviewRedactionFunc = preview
+	m.ReviewRedaction()
+	if m.State != StateLoading || m.RedactionPreview != nil {
+		t.Errorf("State = %v, RedactionPreview = %v, want the question sent", m.State, m.RedactionPreview)
+	}
+
+	// Secrets are listed, and the question waits for Enter
+	m = NewModel("why?", ".env", "DB_PASSWORD=hunter2", nil, nil, nil)
+	m.PreviewRedactionFunc = preview
+	m.ReviewRedaction()
+	if m.State != StateInput || m.Input.Value() != "why?" || len(m.RedactionPreview) != 1 {
+		t.Fatalf("State = %v, Input = %q, RedactionPreview = %v, want the question held", m.State, m.Input.Value(), m.RedactionPreview)
+	}
+	model, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := model.(Model).State; got != StateLoading || cmd == nil {
+		t.Errorf("State = %v after Enter, want the question sent", got)
+	}
+
+	// Attaching a file updates the preview
+	m = NewModel("", "", "", nil, nil, nil)
+	m.PreviewRedactionFunc = preview
+	m.addAttachment(".env", "DB_PASSWORD=hunter2", attach.Stats{})
+	if len(m.RedactionPreview) != 1 {
+		t.Errorf("RedactionPreview = %v after attaching a secret", m.RedactionPreview)
+	}
+}
@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestInsertIntoOutputPath(t *testing.T) {
+	var model tea.Model = NewModel("list files", "", "", nil, nil, nil)
+	model, _ = model.Update(SuggestionMsg("```bash\nls -la\n```"))
+	model, _ = model.Update(SuccessTimeoutMsg{})
+
+	m := model.(Model)
+	m.OutputPath = filepath.Join(t.TempDir(), "cmd")
+	if got := m.optionLabel(m.Options[m.SelectedOption]); got != "Insert" {
+		t.Errorf("option = %q, want Insert with an output path", got)
+	}
+	model, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := model.(Model).Action; got != "insert" {
+		t.Errorf("Action = %q, want insert", got)
+	}
+	if cmd == nil {
+		t.Fatal("inserting didn't quit")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("inserting didn't quit")
+	}
+	got, err := os.ReadFile(m.OutputPath)
+	if err != nil || string(got) != "ls -la" {
+		t.Errorf("output file = %q, %v, want the command", got, err)
+	}
+
+	// A path that can't be written is an error, not a silent quit
+	m.OutputPath = filepath.Join(t.TempDir(), "missing", "cmd")
+	model, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := model.(Model).State; got != StateError {
+		t.Errorf("State = %v, want StateError", got)
+	}
+}
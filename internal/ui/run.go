@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+
+	"huh/internal/risk"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxRunOutput caps how much of a command's output is kept for display and
+// for attaching to follow-up questions. The tail is what matters on failure.
+const maxRunOutput = 32 * 1024
+
+type RunDoneMsg struct {
+	Command     string
+	Output      string
+	ExitCode    int
+	Err         error // Set if the command could not be started
+	Interactive bool  // It had the terminal to itself, Output wasn't captured
+}
+
+// interactiveCommands need a terminal for their output, not a pipe: pagers,
+// editors and full-screen programs.
+var interactiveCommands = map[string]bool{
+	"less": true, "more": true, "most": true, "man": true,
+	"vi": true, "vim": true, "nvim": true, "nano": true, "emacs": true, "micro": true,
+	"top": true, "htop": true, "btop": true, "watch": true, "tmux": true, "screen": true,
+	"ssh": true, "mysql": true, "psql": true, "sqlite3": true, "fzf": true, "ncdu": true,
+}
+
+// isInteractive reports whether command runs any of the
+// interactiveCommands, looking through sudo and similar wrappers.
+func isInteractive(command string) bool {
+	return slices.ContainsFunc(risk.Commands(command), func(name string) bool {
+		return interactiveCommands[name]
+	})
+}
+
+// runCommand runs command in the user's shell with the terminal handed over
+// to it. Output is shown as usual and also captured, except for
+// interactive commands, which get the terminal itself.
+func runCommand(shell, command string) tea.Cmd {
+	if shell == "" {
+		shell = "sh"
+	}
+	flag := "-c"
+	if shell == "powershell" || shell == "pwsh" {
+		flag = "-Command"
+	}
+
+	c := exec.Command(shell, flag, command)
+	if isInteractive(command) {
+		// Left unset, ExecProcess connects them to the terminal
+		return tea.ExecProcess(c, func(err error) tea.Msg {
+			msg := runDone(command, "", err)
+			msg.Interactive = true
+			return msg
+		})
+	}
+
+	out := &tailBuffer{max: maxRunOutput}
+	c.Stdout = io.MultiWriter(os.Stderr, out)
+	c.Stderr = io.MultiWriter(os.Stderr, out)
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		return runDone(command, out.String(), err)
+	})
+}
+
+// runDone turns how command ended into a RunDoneMsg.
+func runDone(command, output string, err error) RunDoneMsg {
+	msg := RunDoneMsg{Command: command, Output: output}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		msg.ExitCode = exitErr.ExitCode()
+	default:
+		msg.ExitCode = -1
+		msg.Err = err
+	}
+	return msg
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = append([]byte(nil), t.buf[len(t.buf)-t.max:]...)
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{max: 10}
+	b.Write([]byte("hello "))
+	if got := b.String(); got != "hello " {
+		t.Errorf("String() = %q", got)
+	}
+	b.Write([]byte("world, again"))
+	if got := b.String(); got != "rld, again" {
+		t.Errorf("String() = %q, want the last 10 bytes", got)
+	}
+	n, err := b.Write([]byte(strings.Repeat("x", 25)))
+	if n != 25 || err != nil {
+		t.Errorf("Write() = %d, %v, want every byte accepted", n, err)
+	}
+	if got := b.String(); got != strings.Repeat("x", 10) {
+		t.Errorf("String() = %q after a write larger than max", got)
+	}
+}
+
+func TestRunDone(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      *exec.Cmd
+		wantCode int
+		wantErr  bool
+	}{
+		{"success", exec.Command("sh", "-c", "true"), 0, false},
+		{"exit status", exec.Command("sh", "-c", "exit 3"), 3, false},
+		{"not started", exec.Command("/nonexistent/huh-test"), -1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := runDone("cmd", "out", tt.cmd.Run())
+			if msg.ExitCode != tt.wantCode || (msg.Err != nil) != tt.wantErr {
+				t.Errorf("runDone() = exit %d, err %v, want exit %d", msg.ExitCode, msg.Err, tt.wantCode)
+			}
+			if msg.Command != "cmd" || msg.Output != "out" {
+				t.Errorf("runDone() = %+v", msg)
+			}
+		})
+	}
+}
+
+func TestRunDoneMsg(t *testing.T) {
+	m := NewModel("q", "", "", nil, nil, nil)
+	m.State = StateSuggestion
+
+	model, _ := m.Update(RunDoneMsg{Command: "true"})
+	m = model.(Model)
+	if m.State != StateRunResult || strings.Join(m.RunOptions, ",") != "Back,Quit" {
+		t.Errorf("State = %v, RunOptions = %v after success", m.State, m.RunOptions)
+	}
+
+	// A failure can be asked about, with its output attached
+	model, _ = m.Update(RunDoneMsg{Command: "make", Output: "no rule\n", ExitCode: 2})
+	m = model.(Model)
+	if m.RunOptions[0] != "Ask about this output" {
+		t.Fatalf("RunOptions = %v after a failure", m.RunOptions)
+	}
+	model, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = model.(Model)
+	if m.State != StateRefining || !strings.Contains(m.ContextContent, "Output of: make (exit code 2) ---\nno rule") {
+		t.Errorf("State = %v, ContextContent = %q", m.State, m.ContextContent)
+	}
+	if !strings.Contains(m.Input.Value(), "exit code 2") {
+		t.Errorf("Input = %q, want the exit code mentioned", m.Input.Value())
+	}
+}
+
+func TestIsInteractive(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"ls -la", false},
+		{"journalctl -u nginx | less", true},
+		{"sudo vim /etc/hosts", true},
+		{"PAGER=cat LESS=-R less README.md", true},
+		{"git log --oneline", false},
+		{"cd /tmp && htop", true},
+		{"/usr/bin/top", true},
+		{"sudo -u root vim /etc/hosts", true},
+		{"env -C /tmp less README.md", true},
+		{`echo "press q to quit less"`, false},
+		{`grep 'x;top' notes.txt`, false},
+	}
+	for _, tt := range tests {
+		if got := isInteractive(tt.command); got != tt.want {
+			t.Errorf("isInteractive(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
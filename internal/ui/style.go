@@ -8,6 +8,7 @@ var (
 	secondaryColor = lipgloss.Color("#04B575") // Green
 	subtleColor    = lipgloss.Color("#6B6B6B") // Grey
 	errorColor     = lipgloss.Color("#FF3333") // Red
+	warningColor   = lipgloss.Color("#FFB000") // Amber
 
 	// Text Styles
 	TitleStyle = lipgloss.NewStyle().
@@ -32,6 +33,14 @@ var (
 				Foreground(subtleColor).
 				Italic(true)
 
+	// Risk Badges
+	HighRiskStyle = lipgloss.NewStyle().
+			Foreground(errorColor).
+			Bold(true)
+
+	MediumRiskStyle = lipgloss.NewStyle().
+			Foreground(warningColor)
+
 	// List Styles
 	SelectedItemStyle = lipgloss.NewStyle().
 				PaddingLeft(2).
@@ -1,6 +1,7 @@
 package usercontext
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"runtime"
@@ -10,26 +11,29 @@ import (
 )
 
 type SystemContext struct {
-	OS          string
-	Distro      string
-	Shell       string
-	PackageMgr  string
-	Hardware    string            // CPU/RAM (if enabled)
-	Clipboard   string            // Detected clipboard tool
-	Custom      map[string]string // User-defined context
+	OS               string
+	Distro           string   // Pretty name, e.g. "Ubuntu 24.04 LTS"
+	DistroID         string   // os-release ID, e.g. "ubuntu"
+	DistroLike       []string // os-release ID_LIKE, e.g. ["debian"]
+	DistroVersion    string   // os-release VERSION_ID
+	Shell            string
+	PackageMgr       string
+	ExtraPackageMgrs []string          // snap, flatpak, ... installed next to PackageMgr
+	InitSystem       string            // systemd, openrc, runit, launchd, ...
+	ContainerRuntime string            // docker, podman or nerdctl, if installed
+	Container        string            // Set when huh itself runs in a container
+	Hardware         *Hardware         // Only detected with context level "hardware"
+	Git              *GitRepo          // Only detected with "git: true", nil outside a repository
+	Project          *Project          // Only detected with "project: true", nil without marker files
+	Clipboard        string            // Detected clipboard tool
+	Custom           map[string]string // User-defined context
 }
 
 func GetContext() SystemContext {
-	ctx := SystemContext{
-		OS:     runtime.GOOS,
-		Custom: make(map[string]string),
-	}
-
-	// 1. Detect Distro
+	// 1. Detect Distro, package managers, ...
+	ctx := detect(hostPlatform(runtime.GOOS))
 	if ctx.OS == "linux" {
-		ctx.Distro = getDistroName()
-		ctx.PackageMgr = detectPackageManager()
-		ctx.Clipboard = detectClipboard()
+		ctx.Clipboard = DetectClipboard()
 	}
 
 	// 2. Detect Shell
@@ -50,13 +54,29 @@ func GetContext() SystemContext {
 		ctx.Hardware = getHardwareInfo()
 	}
 
-	// 4. Merge Config Context (Overrides and Custom)
+	// 4. Git repository of the working directory (if configured)
+	if enabled(config.AppConfig.Context["git"]) {
+		if dir, err := os.Getwd(); err == nil {
+			ctx.Git = getGitInfo(dir)
+		}
+	}
+
+	// 5. Toolchains of the project in the working directory (if configured)
+	if enabled(config.AppConfig.Context["project"]) {
+		if dir, err := os.Getwd(); err == nil {
+			ctx.Project = getProjectInfo(dir)
+		}
+	}
+
+	// 6. Merge Config Context (Overrides and Custom)
 	for k, v := range config.AppConfig.Context {
 		switch k {
 		case "os":
 			ctx.OS = v
 		case "distro":
+			// The detected os-release details describe a different distro now
 			ctx.Distro = v
+			ctx.DistroID, ctx.DistroLike, ctx.DistroVersion = "", nil, ""
 		case "shell":
 			ctx.Shell = v
 		case "package_mgr":
@@ -69,7 +89,7 @@ func GetContext() SystemContext {
 			}
 		case "clipboard":
 			ctx.Clipboard = v
-		case "level":
+		case "level", "git", "project":
 			// ignore, used for logic
 		default:
 			ctx.Custom[k] = v
@@ -79,34 +99,109 @@ func GetContext() SystemContext {
 	return ctx
 }
 
-func getDistroName() string {
-	data, err := os.ReadFile("/etc/os-release")
-	if err != nil {
-		return "linux (unknown)"
+// detect fills in everything that can be read from p.
+func detect(p platform) SystemContext {
+	ctx := SystemContext{
+		OS:     p.goos,
+		Custom: make(map[string]string),
+	}
+	if p.goos == "windows" {
+		return ctx
+	}
+
+	var release OSRelease
+	switch p.goos {
+	case "linux":
+		var ok bool
+		release, ok = p.osRelease()
+		switch {
+		case !ok:
+			ctx.Distro = "linux (unknown)"
+		case release.PrettyName == "":
+			ctx.Distro = "linux"
+		default:
+			ctx.Distro = release.PrettyName
+		}
+		ctx.DistroID = release.ID
+		ctx.DistroLike = release.IDLike
+		ctx.DistroVersion = release.VersionID
+	case "darwin":
+		ctx.Distro = strings.TrimSpace("macOS " + p.macOSVersion())
+	}
+
+	ctx.PackageMgr, ctx.ExtraPackageMgrs = p.packageManagers(release)
+	ctx.InitSystem = p.initSystem()
+	ctx.ContainerRuntime = p.containerRuntime()
+	ctx.Container = p.container()
+	return ctx
+}
+
+// distroFamilies spells the common os-release IDs the way people write them.
+var distroFamilies = map[string]string{
+	"debian":   "Debian",
+	"ubuntu":   "Ubuntu",
+	"rhel":     "RHEL",
+	"fedora":   "Fedora",
+	"centos":   "CentOS",
+	"arch":     "Arch",
+	"suse":     "SUSE",
+	"opensuse": "openSUSE",
+	"alpine":   "Alpine",
+	"gentoo":   "Gentoo",
+}
+
+// DistroDescription is Distro with the os-release details, e.g.
+// "Ubuntu 24.04 LTS (ubuntu 24.04, Debian-like)".
+func (c SystemContext) DistroDescription() string {
+	var details []string
+	if c.DistroID != "" {
+		details = append(details, strings.TrimSpace(c.DistroID+" "+c.DistroVersion))
 	}
-	content := string(data)
-	for _, line := range strings.Split(content, "\n") {
-		if strings.HasPrefix(line, "PRETTY_NAME=") {
-			return strings.Trim(strings.TrimPrefix(line, "PRETTY_NAME="), "\"")
+	if len(c.DistroLike) > 0 {
+		var families []string
+		for _, id := range c.DistroLike {
+			if name, ok := distroFamilies[id]; ok {
+				id = name
+			}
+			families = append(families, id)
 		}
+		details = append(details, strings.Join(families, "/")+"-like")
+	}
+	if len(details) == 0 {
+		return c.Distro
 	}
-	return "linux"
+	return fmt.Sprintf("%s (%s)", c.Distro, strings.Join(details, ", "))
 }
 
-func detectPackageManager() string {
-	if _, err := exec.LookPath("pacman"); err == nil {
-		return "pacman"
+// Describe summarizes the system for the prompt.
+func (c SystemContext) Describe() string {
+	parts := []string{"OS: " + c.OS}
+	if c.Distro != "" {
+		parts = append(parts, "Distro: "+c.DistroDescription())
 	}
-	if _, err := exec.LookPath("apt"); err == nil {
-		return "apt"
+	parts = append(parts, "Shell: "+c.Shell)
+	if c.PackageMgr != "" {
+		pm := "Package manager: " + c.PackageMgr
+		if len(c.ExtraPackageMgrs) > 0 {
+			pm += " (also " + strings.Join(c.ExtraPackageMgrs, ", ") + ")"
+		}
+		parts = append(parts, pm)
 	}
-	if _, err := exec.LookPath("dnf"); err == nil {
-		return "dnf"
+	if c.InitSystem != "" && c.InitSystem != "unknown" {
+		parts = append(parts, "Init: "+c.InitSystem)
 	}
-	return "unknown"
+	if c.ContainerRuntime != "" {
+		parts = append(parts, "Container runtime: "+c.ContainerRuntime)
+	}
+	if c.Container != "" {
+		parts = append(parts, "Running inside a "+c.Container+" container")
+	}
+	return strings.Join(parts, ", ")
 }
 
-func detectClipboard() string {
+// DetectClipboard names the clipboard tool copying uses on Linux, in the
+// order the clipboard package tries them, or "unknown" if there is none.
+func DetectClipboard() string {
 	// Wayland check
 	if os.Getenv("WAYLAND_DISPLAY") != "" {
 		if _, err := exec.LookPath("wl-copy"); err == nil {
@@ -114,14 +209,10 @@ func detectClipboard() string {
 		}
 	}
 	// X11 / standard
-	if _, err := exec.LookPath("xclip"); err == nil {
-		return "xclip"
+	for _, tool := range []string{"xclip", "xsel", "termux-clipboard-set"} {
+		if _, err := exec.LookPath(tool); err == nil {
+			return tool
+		}
 	}
-	return "unknown" 
-}
-
-func getHardwareInfo() string {
-	// Simplified implementation
-	// In a real app, we might parse /proc/cpuinfo or use a library
-	return "CPU: detected, RAM: detected" // Placeholder
+	return "unknown"
 }
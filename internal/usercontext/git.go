@@ -0,0 +1,166 @@
+package usercontext
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitTimeout keeps a huge working tree from holding up the question.
+const gitTimeout = 2 * time.Second
+
+// GitRepo describes the repository the user is asking from. Everything is
+// read locally, nothing is fetched.
+type GitRepo struct {
+	Root      string
+	Branch    string // Empty when HEAD is detached
+	Commit    string // Short hash of HEAD, empty before the first commit
+	Upstream  string
+	Ahead     int
+	Behind    int
+	Changed   int // Staged, modified and conflicted files
+	Untracked int
+	State     string // "rebase", "merge", "cherry-pick", ... while in progress
+	Remotes   []string
+}
+
+// Dirty reports whether there is anything to commit.
+func (g *GitRepo) Dirty() bool {
+	return g.Changed > 0 || g.Untracked > 0
+}
+
+// String summarizes the repository for the prompt.
+func (g *GitRepo) String() string {
+	var parts []string
+	switch {
+	case g.Branch != "":
+		branch := "branch " + g.Branch
+		if g.Upstream != "" {
+			branch += " tracking " + g.Upstream
+			if g.Ahead > 0 || g.Behind > 0 {
+				branch += fmt.Sprintf(" (%d ahead, %d behind)", g.Ahead, g.Behind)
+			}
+		} else {
+			branch += " with no upstream"
+		}
+		parts = append(parts, branch)
+	case g.Commit != "":
+		parts = append(parts, "detached HEAD at "+g.Commit)
+	}
+	if g.Commit == "" && g.Branch != "" {
+		parts = append(parts, "no commits yet")
+	}
+
+	if g.Dirty() {
+		parts = append(parts, fmt.Sprintf("dirty (%d changed, %d untracked)", g.Changed, g.Untracked))
+	} else {
+		parts = append(parts, "clean")
+	}
+	if g.State != "" {
+		parts = append(parts, g.State+" in progress")
+	}
+	if len(g.Remotes) > 0 {
+		parts = append(parts, "remotes: "+strings.Join(g.Remotes, ", "))
+	} else {
+		parts = append(parts, "no remotes")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// enabled reports whether a context switch like "git: true" is on.
+func enabled(value string) bool {
+	on, _ := strconv.ParseBool(value)
+	return on
+}
+
+// getGitInfo returns the repository dir is in, or nil outside a repository
+// or without git.
+func getGitInfo(dir string) *GitRepo {
+	return gitInfo(func(args ...string) (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir, "--no-optional-locks"}, args...)...)
+		// Never stop to ask for credentials or anything else
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "LC_ALL=C")
+		out, err := cmd.Output()
+		return string(out), err
+	})
+}
+
+// gitInfo asks git through run, which gets the arguments after "git".
+func gitInfo(run func(args ...string) (string, error)) *GitRepo {
+	out, err := run("rev-parse", "--absolute-git-dir", "--show-toplevel")
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	gitDir := lines[0]
+	repo := &GitRepo{}
+	if len(lines) > 1 {
+		repo.Root = lines[1]
+	}
+
+	if out, err := run("status", "--porcelain=v2", "--branch"); err == nil {
+		parseGitStatus(out, repo)
+	}
+	if out, err := run("remote"); err == nil {
+		repo.Remotes = strings.Fields(out)
+	}
+	repo.State = gitState(gitDir)
+	return repo
+}
+
+// parseGitStatus reads the output of "git status --porcelain=v2 --branch".
+func parseGitStatus(out string, repo *GitRepo) {
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "# branch.oid "):
+			if oid := strings.TrimPrefix(line, "# branch.oid "); oid != "(initial)" {
+				repo.Commit = oid[:min(len(oid), 7)]
+			}
+		case strings.HasPrefix(line, "# branch.head "):
+			if head := strings.TrimPrefix(line, "# branch.head "); head != "(detached)" {
+				repo.Branch = head
+			}
+		case strings.HasPrefix(line, "# branch.upstream "):
+			repo.Upstream = strings.TrimPrefix(line, "# branch.upstream ")
+		case strings.HasPrefix(line, "# branch.ab "):
+			fmt.Sscanf(strings.TrimPrefix(line, "# branch.ab "), "+%d -%d", &repo.Ahead, &repo.Behind)
+		case strings.HasPrefix(line, "? "):
+			repo.Untracked++
+		case strings.HasPrefix(line, "1 "), strings.HasPrefix(line, "2 "), strings.HasPrefix(line, "u "):
+			repo.Changed++
+		}
+	}
+}
+
+// gitState looks for the marker files git leaves in gitDir while an
+// operation is stopped halfway.
+func gitState(gitDir string) string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(gitDir, name))
+		return err == nil
+	}
+	switch {
+	case exists("rebase-merge"):
+		return "rebase"
+	case exists("rebase-apply/applying"):
+		return "am"
+	case exists("rebase-apply"):
+		return "rebase"
+	case exists("MERGE_HEAD"):
+		return "merge"
+	case exists("CHERRY_PICK_HEAD"):
+		return "cherry-pick"
+	case exists("REVERT_HEAD"):
+		return "revert"
+	case exists("BISECT_LOG"):
+		return "bisect"
+	}
+	return ""
+}
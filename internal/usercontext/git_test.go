@@ -0,0 +1,94 @@
+package usercontext
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseGitStatus(t *testing.T) {
+	out := `# branch.oid 4f3c2a1b9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b
+# branch.head feature/login
+# branch.upstream origin/feature/login
+# branch.ab +2 -1
+1 .M N... 100644 100644 100644 3f2a 3f2a README.md
+2 R. N... 100644 100644 100644 3f2a 3f2a R100 new.go	old.go
+u UU N... 100644 100644 100644 100644 1a 2b 3c conflict.go
+? notes.txt
+? tmp/
+`
+	var got GitRepo
+	parseGitStatus(out, &got)
+	want := GitRepo{
+		Branch:    "feature/login",
+		Commit:    "4f3c2a1",
+		Upstream:  "origin/feature/login",
+		Ahead:     2,
+		Behind:    1,
+		Changed:   3,
+		Untracked: 2,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGitStatus() = %+v, want %+v", got, want)
+	}
+
+	want.Remotes = []string{"origin", "upstream"}
+	want.State = "rebase"
+	if s := want.String(); s != "branch feature/login tracking origin/feature/login (2 ahead, 1 behind); dirty (3 changed, 2 untracked); rebase in progress; remotes: origin, upstream" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestGetGitInfo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=huh", "-c", "user.email=huh@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	if repo := getGitInfo(dir); repo != nil {
+		t.Fatalf("getGitInfo() outside a repository = %+v, want nil", repo)
+	}
+
+	git("init", "-q", "-b", "main")
+	repo := getGitInfo(dir)
+	if repo == nil || repo.Branch != "main" || repo.Commit != "" || repo.Dirty() {
+		t.Fatalf("getGitInfo() in an empty repository = %+v", repo)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "a.txt")
+	git("commit", "-q", "-m", "first")
+	git("remote", "add", "origin", "https://example.com/repo.git")
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".git", "MERGE_HEAD"), []byte("0000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo = getGitInfo(dir)
+	if repo == nil {
+		t.Fatal("getGitInfo() = nil")
+	}
+	if repo.Commit == "" || repo.Untracked != 1 || repo.Changed != 0 {
+		t.Errorf("getGitInfo() = %+v, want one untracked file after a commit", repo)
+	}
+	if repo.State != "merge" {
+		t.Errorf("State = %q, want merge", repo.State)
+	}
+	if !reflect.DeepEqual(repo.Remotes, []string{"origin"}) {
+		t.Errorf("Remotes = %v, want [origin]", repo.Remotes)
+	}
+}
@@ -0,0 +1,372 @@
+package usercontext
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Hardware describes the machine, for questions where it matters (which
+// driver to install, how many jobs to run, which disk to use, ...).
+type Hardware struct {
+	Arch         string
+	CPUModel     string
+	CPUCores     int    // Physical cores, or CPUThreads if unknown
+	CPUThreads   int    // Logical CPUs
+	MemTotal     uint64 // Bytes
+	MemAvailable uint64 // Bytes, left out of the prompt as it changes every run
+	Mounts       []Mount
+	GPUs         []GPU
+}
+
+// Mount is a mounted disk or network filesystem.
+type Mount struct {
+	Device string
+	Path   string
+	FSType string
+}
+
+type GPU struct {
+	Name   string // e.g. "card0" on Linux, the chipset on macOS
+	Vendor string
+	Driver string
+}
+
+// maxPromptMounts keeps the prompt short on machines with many mounts.
+const maxPromptMounts = 8
+
+// String summarizes the hardware for the system prompt. It only holds
+// facts that stay the same between runs, or the response cache would
+// never hit.
+func (h *Hardware) String() string {
+	var parts []string
+	if h.CPUModel != "" || h.CPUThreads > 0 {
+		cpu := h.CPUModel
+		if cpu == "" {
+			cpu = "unknown"
+		}
+		switch {
+		case h.CPUCores > 0 && h.CPUCores != h.CPUThreads:
+			cpu += fmt.Sprintf(" (%d cores, %d threads)", h.CPUCores, h.CPUThreads)
+		case h.CPUThreads > 0:
+			cpu += fmt.Sprintf(" (%d cores)", h.CPUThreads)
+		}
+		parts = append(parts, "CPU: "+cpu)
+	}
+	if h.MemTotal > 0 {
+		parts = append(parts, "RAM: "+formatBytes(h.MemTotal)+" total")
+	}
+	if h.Arch != "" {
+		parts = append(parts, "Arch: "+h.Arch)
+	}
+	if len(h.GPUs) > 0 {
+		var gpus []string
+		for _, g := range h.GPUs {
+			desc := g.Vendor
+			if desc == "" {
+				desc = g.Name
+			}
+			if g.Driver != "" {
+				desc += " (" + g.Driver + ")"
+			}
+			gpus = append(gpus, desc)
+		}
+		parts = append(parts, "GPU: "+strings.Join(gpus, ", "))
+	} else {
+		parts = append(parts, "GPU: none detected")
+	}
+	if len(h.Mounts) > 0 {
+		var mounts []string
+		for i, m := range h.Mounts {
+			if i == maxPromptMounts {
+				mounts = append(mounts, fmt.Sprintf("and %d more", len(h.Mounts)-i))
+				break
+			}
+			mounts = append(mounts, fmt.Sprintf("%s %s on %s", m.Path, m.FSType, m.Device))
+		}
+		parts = append(parts, "Disks: "+strings.Join(mounts, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func formatBytes(b uint64) string {
+	const gib = 1 << 30
+	if b >= gib {
+		return fmt.Sprintf("%.1f GiB", float64(b)/gib)
+	}
+	return fmt.Sprintf("%d MiB", b>>20)
+}
+
+func getHardwareInfo() *Hardware {
+	var hw *Hardware
+	switch runtime.GOOS {
+	case "linux":
+		hw = linuxHardware(os.DirFS("/"))
+	case "darwin":
+		hw = darwinHardware(runOutput)
+	default:
+		hw = &Hardware{CPUThreads: runtime.NumCPU()}
+	}
+	hw.Arch = runtime.GOARCH
+	return hw
+}
+
+func runOutput(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).Output()
+	return string(out), err
+}
+
+// linuxHardware reads /proc and /sys from fsys, which is rooted at "/".
+func linuxHardware(fsys fs.FS) *Hardware {
+	hw := &Hardware{}
+	readCPUInfo(fsys, hw)
+	readMemInfo(fsys, hw)
+	hw.Mounts = readMounts(fsys)
+	hw.GPUs = readDRM(fsys)
+	return hw
+}
+
+func readCPUInfo(fsys fs.FS, hw *Hardware) {
+	f, err := fsys.Open("proc/cpuinfo")
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	// x86 reports "cpu cores" per "physical id"; other architectures
+	// only list the processors.
+	coresPerSocket := map[string]int{}
+	socket := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "processor":
+			hw.CPUThreads++
+		case "model name", "Model", "Hardware":
+			if hw.CPUModel == "" {
+				hw.CPUModel = value
+			}
+		case "physical id":
+			socket = value
+		case "cpu cores":
+			if n, err := strconv.Atoi(value); err == nil {
+				coresPerSocket[socket] = n
+			}
+		}
+	}
+
+	for _, n := range coresPerSocket {
+		hw.CPUCores += n
+	}
+	if hw.CPUCores == 0 {
+		hw.CPUCores = hw.CPUThreads
+	}
+}
+
+func readMemInfo(fsys fs.FS, hw *Hardware) {
+	data, err := fs.ReadFile(fsys, "proc/meminfo")
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			hw.MemTotal = kb * 1024
+		case "MemAvailable:":
+			hw.MemAvailable = kb * 1024
+		}
+	}
+}
+
+// networkFS are the filesystem types kept even though they aren't backed
+// by a device under /dev.
+var networkFS = map[string]bool{
+	"nfs": true, "nfs4": true, "cifs": true, "smb3": true, "fuse.sshfs": true, "zfs": true,
+}
+
+// readMounts lists the disk and network mounts, skipping virtual ones
+// (proc, tmpfs, overlay, snap loop devices, ...).
+func readMounts(fsys fs.FS) []Mount {
+	data, err := fs.ReadFile(fsys, "proc/mounts")
+	if err != nil {
+		return nil
+	}
+	var mounts []Mount
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		device, fsType := fields[0], fields[2]
+		isDisk := strings.HasPrefix(device, "/dev/") && !strings.HasPrefix(device, "/dev/loop")
+		if !isDisk && !networkFS[fsType] {
+			continue
+		}
+		mounts = append(mounts, Mount{
+			Device: unescapeMount(device),
+			Path:   unescapeMount(fields[1]),
+			FSType: fsType,
+		})
+	}
+	return mounts
+}
+
+// unescapeMount decodes the octal escapes (\040 for space, ...) used in
+// /proc/mounts.
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// pciVendors names the GPU vendors worth telling apart.
+var pciVendors = map[string]string{
+	"0x10de": "NVIDIA",
+	"0x1002": "AMD",
+	"0x8086": "Intel",
+	"0x1af4": "virtio",
+	"0x15ad": "VMware",
+}
+
+// readDRM lists the GPUs under /sys/class/drm. Connectors (card0-HDMI-A-1)
+// and render nodes are skipped.
+func readDRM(fsys fs.FS) []GPU {
+	entries, err := fs.ReadDir(fsys, "sys/class/drm")
+	if err != nil {
+		return nil
+	}
+	var gpus []GPU
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, "card") || strings.Contains(name, "-") {
+			continue
+		}
+		gpu := GPU{Name: name}
+		dir := path.Join("sys/class/drm", name, "device")
+		if vendor, err := fs.ReadFile(fsys, path.Join(dir, "vendor")); err == nil {
+			id := strings.TrimSpace(string(vendor))
+			gpu.Vendor = pciVendors[id]
+			if gpu.Vendor == "" {
+				gpu.Vendor = id
+			}
+		}
+		if uevent, err := fs.ReadFile(fsys, path.Join(dir, "uevent")); err == nil {
+			for _, line := range strings.Split(string(uevent), "\n") {
+				if driver, ok := strings.CutPrefix(line, "DRIVER="); ok {
+					gpu.Driver = driver
+				}
+			}
+		}
+		gpus = append(gpus, gpu)
+	}
+	return gpus
+}
+
+// darwinHardware asks sysctl and friends through run.
+func darwinHardware(run func(name string, args ...string) (string, error)) *Hardware {
+	hw := &Hardware{}
+	sysctl := func(key string) string {
+		out, err := run("sysctl", "-n", key)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(out)
+	}
+
+	hw.CPUModel = sysctl("machdep.cpu.brand_string")
+	hw.CPUCores, _ = strconv.Atoi(sysctl("hw.physicalcpu"))
+	hw.CPUThreads, _ = strconv.Atoi(sysctl("hw.logicalcpu"))
+	hw.MemTotal, _ = strconv.ParseUint(sysctl("hw.memsize"), 10, 64)
+
+	if out, err := run("vm_stat"); err == nil {
+		hw.MemAvailable = parseVMStat(out)
+	}
+	if out, err := run("mount"); err == nil {
+		hw.Mounts = parseDarwinMounts(out)
+	}
+	if out, err := run("system_profiler", "SPDisplaysDataType"); err == nil {
+		for _, line := range strings.Split(out, "\n") {
+			if model, ok := strings.CutPrefix(strings.TrimSpace(line), "Chipset Model:"); ok {
+				hw.GPUs = append(hw.GPUs, GPU{Name: strings.TrimSpace(model)})
+			}
+		}
+	}
+	return hw
+}
+
+// parseVMStat counts free and inactive pages as available, which is
+// roughly what Activity Monitor reports.
+func parseVMStat(out string) uint64 {
+	var pageSize, pages uint64
+	for _, line := range strings.Split(out, "\n") {
+		if rest, ok := strings.CutPrefix(line, "Mach Virtual Memory Statistics: (page size of "); ok {
+			if fields := strings.Fields(rest); len(fields) > 0 {
+				pageSize, _ = strconv.ParseUint(fields[0], 10, 64)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Pages free", "Pages inactive", "Pages speculative":
+			n, _ := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), "."), 10, 64)
+			pages += n
+		}
+	}
+	return pages * pageSize
+}
+
+// parseDarwinMounts reads `mount` output, e.g.
+// "/dev/disk3s1s1 on / (apfs, sealed, local, read-only, journaled)".
+func parseDarwinMounts(out string) []Mount {
+	var mounts []Mount
+	for _, line := range strings.Split(out, "\n") {
+		device, rest, ok := strings.Cut(line, " on ")
+		if !ok {
+			continue
+		}
+		mountPath, opts, ok := strings.Cut(rest, " (")
+		if !ok {
+			continue
+		}
+		fsType, _, _ := strings.Cut(strings.TrimSuffix(opts, ")"), ",")
+		if !strings.HasPrefix(device, "/dev/") && !networkFS[fsType] && fsType != "smbfs" {
+			continue
+		}
+		mounts = append(mounts, Mount{Device: device, Path: mountPath, FSType: fsType})
+	}
+	return mounts
+}
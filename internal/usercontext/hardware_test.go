@@ -0,0 +1,138 @@
+package usercontext
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLinuxHardware(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    Hardware
+	}{
+		{
+			fixture: "linux-x86",
+			want: Hardware{
+				CPUModel:     "AMD Ryzen 7 5800X 8-Core Processor",
+				CPUCores:     2,
+				CPUThreads:   4,
+				MemTotal:     32768000 * 1024,
+				MemAvailable: 16384000 * 1024,
+				Mounts: []Mount{
+					{Device: "/dev/nvme0n1p2", Path: "/", FSType: "ext4"},
+					{Device: "/dev/nvme0n1p1", Path: "/boot/efi", FSType: "vfat"},
+					{Device: "/dev/sda1", Path: "/mnt/My Backup", FSType: "ext4"},
+					{Device: "nas:/export", Path: "/mnt/nas", FSType: "nfs4"},
+				},
+				GPUs: []GPU{{Name: "card0", Vendor: "AMD", Driver: "amdgpu"}},
+			},
+		},
+		{
+			fixture: "linux-arm",
+			want: Hardware{
+				CPUModel:     "Raspberry Pi 4 Model B Rev 1.1",
+				CPUCores:     2,
+				CPUThreads:   2,
+				MemTotal:     3884512 * 1024,
+				MemAvailable: 3402532 * 1024,
+				Mounts: []Mount{
+					{Device: "/dev/mmcblk0p2", Path: "/", FSType: "ext4"},
+					{Device: "/dev/mmcblk0p1", Path: "/boot", FSType: "vfat"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			got := linuxHardware(os.DirFS("testdata/" + tt.fixture))
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("linuxHardware() =\n%+v\nwant\n%+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDarwinHardware(t *testing.T) {
+	outputs := map[string]string{
+		"sysctl -n machdep.cpu.brand_string": "Apple M2 Pro\n",
+		"sysctl -n hw.physicalcpu":           "10\n",
+		"sysctl -n hw.logicalcpu":            "10\n",
+		"sysctl -n hw.memsize":               "17179869184\n",
+		"vm_stat": "Mach Virtual Memory Statistics: (page size of 16384 bytes)\n" +
+			"Pages free:                               10000.\n" +
+			"Pages active:                            300000.\n" +
+			"Pages inactive:                          200000.\n" +
+			"Pages speculative:                         5000.\n",
+		"mount": "/dev/disk3s1s1 on / (apfs, sealed, local, read-only, journaled)\n" +
+			"devfs on /dev (devfs, local, nobrowse)\n" +
+			"/dev/disk3s5 on /System/Volumes/Data (apfs, local, journaled, nobrowse)\n",
+		"system_profiler SPDisplaysDataType": "Graphics/Displays:\n\n    Apple M2 Pro:\n\n      Chipset Model: Apple M2 Pro\n      Type: GPU\n",
+	}
+	run := func(name string, args ...string) (string, error) {
+		cmd := strings.Join(append([]string{name}, args...), " ")
+		if out, ok := outputs[cmd]; ok {
+			return out, nil
+		}
+		return "", fmt.Errorf("unexpected command %q", cmd)
+	}
+
+	got := darwinHardware(run)
+	want := Hardware{
+		CPUModel:     "Apple M2 Pro",
+		CPUCores:     10,
+		CPUThreads:   10,
+		MemTotal:     17179869184,
+		MemAvailable: 215000 * 16384,
+		Mounts: []Mount{
+			{Device: "/dev/disk3s1s1", Path: "/", FSType: "apfs"},
+			{Device: "/dev/disk3s5", Path: "/System/Volumes/Data", FSType: "apfs"},
+		},
+		GPUs: []GPU{{Name: "Apple M2 Pro"}},
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("darwinHardware() =\n%+v\nwant\n%+v", *got, want)
+	}
+}
+
+func TestParseVMStat(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want uint64
+	}{
+		{"pages", "Mach Virtual Memory Statistics: (page size of 4096 bytes)\nPages free: 10.\nPages inactive: 5.\nPages active: 100.\n", 15 * 4096},
+		{"no page size", "Mach Virtual Memory Statistics: (page size of \nPages free: 10.\n", 0},
+		{"empty", "", 0},
+	}
+	for _, tt := range tests {
+		if got := parseVMStat(tt.out); got != tt.want {
+			t.Errorf("%s: parseVMStat() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHardwareString(t *testing.T) {
+	h := linuxHardware(os.DirFS("testdata/linux-x86"))
+	h.Arch = "amd64"
+	want := "CPU: AMD Ryzen 7 5800X 8-Core Processor (2 cores, 4 threads); RAM: 31.2 GiB total; " +
+		"Arch: amd64; GPU: AMD (amdgpu); " +
+		"Disks: / ext4 on /dev/nvme0n1p2, /boot/efi vfat on /dev/nvme0n1p1, /mnt/My Backup ext4 on /dev/sda1, /mnt/nas nfs4 on nas:/export"
+	if got := h.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := (&Hardware{Arch: "arm64"}).String(); got != "Arch: arm64; GPU: none detected" {
+		t.Errorf("String() of empty hardware = %q", got)
+	}
+
+	// The prompt is part of the cache key, so it can't change between runs
+	before := h.String()
+	h.MemAvailable /= 2
+	if got := h.String(); got != before {
+		t.Errorf("String() changed with available memory:\n%s\n%s", before, got)
+	}
+}
@@ -0,0 +1,120 @@
+package usercontext
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// LastCommand returns the most recent command in the shell's history file,
+// skipping "huh fix" itself. Shells usually write their history on exit, so
+// this can lag behind the current session; the shell integration passes the
+// command directly instead.
+func LastCommand(shell string) (string, error) {
+	path := historyFile(shell)
+	if path == "" {
+		return "", fmt.Errorf("don't know where %s keeps its history", shell)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	entries, err := historyEntries(shell, f)
+	if err != nil {
+		return "", err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !isHuhFix(entries[i]) {
+			return entries[i], nil
+		}
+	}
+	return "", fmt.Errorf("no commands found in %s", path)
+}
+
+func historyFile(shell string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	switch shell {
+	case "bash":
+		if f := os.Getenv("HISTFILE"); f != "" {
+			return f
+		}
+		return filepath.Join(home, ".bash_history")
+	case "zsh":
+		if f := os.Getenv("HISTFILE"); f != "" {
+			return f
+		}
+		return filepath.Join(home, ".zsh_history")
+	case "fish":
+		dataDir := os.Getenv("XDG_DATA_HOME")
+		if dataDir == "" {
+			dataDir = filepath.Join(home, ".local", "share")
+		}
+		return filepath.Join(dataDir, "fish", "fish_history")
+	}
+	return ""
+}
+
+// zshExtended matches the ": <start>:<duration>;" prefix of zsh's
+// EXTENDED_HISTORY format.
+var zshExtended = regexp.MustCompile(`^: \d+:\d+;`)
+
+// bashTimestamp matches the "#<epoch>" lines bash writes with HISTTIMEFORMAT.
+var bashTimestamp = regexp.MustCompile(`^#\d+$`)
+
+// historyEntries parses a history file into commands, oldest first.
+func historyEntries(shell string, r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var entries []string
+	continued := false // zsh ends lines of multi-line entries with "\"
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch shell {
+		case "fish":
+			if cmd, ok := strings.CutPrefix(line, "- cmd: "); ok {
+				entries = append(entries, unescapeFish(cmd))
+			}
+			continue
+		case "bash":
+			if bashTimestamp.MatchString(line) {
+				continue
+			}
+		case "zsh":
+			if !continued {
+				line = zshExtended.ReplaceAllString(line, "")
+			}
+		}
+
+		if continued && len(entries) > 0 {
+			entries[len(entries)-1] += "\n" + line
+		} else if strings.TrimSpace(line) != "" {
+			entries = append(entries, line)
+		}
+		continued = shell == "zsh" && strings.HasSuffix(line, "\\")
+		if continued {
+			last := entries[len(entries)-1]
+			entries[len(entries)-1] = last[:len(last)-1]
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// unescapeFish undoes the escaping fish applies to history entries.
+func unescapeFish(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(s)
+}
+
+func isHuhFix(command string) bool {
+	fields := strings.Fields(command)
+	return len(fields) >= 2 && fields[0] == "huh" && fields[1] == "fix"
+}
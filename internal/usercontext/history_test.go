@@ -0,0 +1,47 @@
+package usercontext
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHistoryEntries(t *testing.T) {
+	tests := []struct {
+		shell string
+		file  string
+		want  []string
+	}{
+		{"bash", "ls\nmake build\n", []string{"ls", "make build"}},
+		{"bash", "#1700000000\nls\n#1700000001\ngit push\n", []string{"ls", "git push"}},
+		{"zsh", ": 1700000000:0;ls\n: 1700000001:2;make\n", []string{"ls", "make"}},
+		{"zsh", "echo a\nfor f in *; do\\\necho $f\\\ndone\n", []string{"echo a", "for f in *; do\necho $f\ndone"}},
+		{"fish", "- cmd: ls\n  when: 1700000000\n- cmd: echo a\\nb\n  when: 1700000001\n  paths:\n    - a\n", []string{"ls", "echo a\nb"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			got, err := historyEntries(tt.shell, strings.NewReader(tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("historyEntries() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsHuhFix(t *testing.T) {
+	for cmd, want := range map[string]bool{
+		"huh fix":           true,
+		"huh fix --print":   true,
+		"huh fix the build": true,
+		"huh how do I fix":  false,
+		"make fix":          false,
+		"huh":               false,
+	} {
+		if got := isHuhFix(cmd); got != want {
+			t.Errorf("isHuhFix(%q) = %v, want %v", cmd, got, want)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package usercontext
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// platform is what detection looks at: the filesystem, rooted at "/", and
+// the PATH. Tests swap both out.
+type platform struct {
+	goos     string
+	fsys     fs.FS
+	lookPath func(string) (string, error)
+}
+
+func hostPlatform(goos string) platform {
+	return platform{goos: goos, fsys: os.DirFS("/"), lookPath: exec.LookPath}
+}
+
+func (p platform) has(cmd string) bool {
+	_, err := p.lookPath(cmd)
+	return err == nil
+}
+
+func (p platform) exists(name string) bool {
+	_, err := fs.Stat(p.fsys, name)
+	return err == nil
+}
+
+// OSRelease holds the fields of /etc/os-release that matter to us.
+type OSRelease struct {
+	PrettyName string
+	ID         string   // e.g. "ubuntu"
+	IDLike     []string // e.g. ["debian"]
+	VersionID  string   // e.g. "24.04"
+}
+
+func parseOSRelease(data string) OSRelease {
+	var r OSRelease
+	for _, line := range strings.Split(data, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "PRETTY_NAME":
+			r.PrettyName = value
+		case "ID":
+			r.ID = strings.ToLower(value)
+		case "ID_LIKE":
+			r.IDLike = strings.Fields(strings.ToLower(value))
+		case "VERSION_ID":
+			r.VersionID = value
+		}
+	}
+	return r
+}
+
+func (p platform) osRelease() (OSRelease, bool) {
+	for _, name := range []string{"etc/os-release", "usr/lib/os-release"} {
+		if data, err := fs.ReadFile(p.fsys, name); err == nil {
+			return parseOSRelease(string(data)), true
+		}
+	}
+	return OSRelease{}, false
+}
+
+// macOSVersion reads the product version from SystemVersion.plist.
+func (p platform) macOSVersion() string {
+	data, err := fs.ReadFile(p.fsys, "System/Library/CoreServices/SystemVersion.plist")
+	if err != nil {
+		return ""
+	}
+	// The plist is flat, so the value simply follows its key
+	_, rest, ok := strings.Cut(string(data), "<key>ProductVersion</key>")
+	if !ok {
+		return ""
+	}
+	_, rest, ok = strings.Cut(rest, "<string>")
+	if !ok {
+		return ""
+	}
+	version, _, _ := strings.Cut(rest, "</string>")
+	return strings.TrimSpace(version)
+}
+
+// packageManager is a system package manager and the command that gives it
+// away.
+type packageManager struct {
+	name string
+	cmd  string
+}
+
+// systemPackageManagers in order of preference when the distro doesn't
+// tell us which one it uses.
+var systemPackageManagers = []packageManager{
+	{"apt", "apt"},
+	{"dnf", "dnf"},
+	{"yum", "yum"},
+	{"pacman", "pacman"},
+	{"zypper", "zypper"},
+	{"apk", "apk"},
+	{"xbps", "xbps-install"},
+	{"emerge", "emerge"},
+	{"nix", "nix-env"},
+	{"brew", "brew"},
+}
+
+// distroPackageManagers maps os-release IDs to their package manager.
+var distroPackageManagers = map[string]string{
+	"debian":   "apt",
+	"ubuntu":   "apt",
+	"fedora":   "dnf",
+	"rhel":     "dnf",
+	"centos":   "dnf",
+	"arch":     "pacman",
+	"opensuse": "zypper",
+	"suse":     "zypper",
+	"sles":     "zypper",
+	"alpine":   "apk",
+	"void":     "xbps",
+	"gentoo":   "emerge",
+	"nixos":    "nix",
+}
+
+// extraPackageManagers are installed next to the system one.
+var extraPackageManagers = []packageManager{
+	{"snap", "snap"},
+	{"flatpak", "flatpak"},
+	{"brew", "brew"},
+	{"nix", "nix-env"},
+}
+
+// packageManagers returns the system package manager and any extra ones
+// that are installed.
+func (p platform) packageManagers(release OSRelease) (string, []string) {
+	primary := ""
+	if p.goos == "darwin" {
+		if p.has("brew") {
+			primary = "brew"
+		} else if p.has("port") {
+			primary = "port"
+		}
+	} else {
+		// Trust the distro first, as long as the tool is really there
+		for _, id := range append([]string{release.ID}, release.IDLike...) {
+			name, ok := distroPackageManagers[id]
+			if !ok {
+				continue
+			}
+			// RHEL-likes before 8 only have yum
+			if name == "dnf" && !p.has("dnf") && p.has("yum") {
+				name = "yum"
+			}
+			for _, pm := range systemPackageManagers {
+				if pm.name == name && p.has(pm.cmd) {
+					primary = name
+				}
+			}
+			if primary != "" {
+				break
+			}
+		}
+		if primary == "" {
+			for _, pm := range systemPackageManagers {
+				if p.has(pm.cmd) {
+					primary = pm.name
+					break
+				}
+			}
+		}
+	}
+
+	var extra []string
+	for _, pm := range extraPackageManagers {
+		if pm.name != primary && !slices.Contains(extra, pm.name) && p.has(pm.cmd) {
+			extra = append(extra, pm.name)
+		}
+	}
+	if primary == "" {
+		primary = "unknown"
+	}
+	return primary, extra
+}
+
+// initSystem works out what runs as PID 1.
+func (p platform) initSystem() string {
+	if p.goos == "darwin" {
+		return "launchd"
+	}
+	if p.exists("run/systemd/system") {
+		return "systemd"
+	}
+	comm, _ := fs.ReadFile(p.fsys, "proc/1/comm")
+	switch strings.TrimSpace(string(comm)) {
+	case "systemd":
+		return "systemd"
+	case "runit", "runit-init":
+		return "runit"
+	case "s6-svscan":
+		return "s6"
+	}
+	if p.exists("run/openrc") || p.exists("sbin/openrc") {
+		return "openrc"
+	}
+	if p.exists("etc/runit") {
+		return "runit"
+	}
+	return "unknown"
+}
+
+// containerRuntime returns the container tool that is installed, preferring
+// the one most people expect "docker run" to map to.
+func (p platform) containerRuntime() string {
+	for _, name := range []string{"docker", "podman", "nerdctl"} {
+		if p.has(name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// container returns the kind of container huh itself runs in, if any.
+func (p platform) container() string {
+	switch {
+	case p.exists(".dockerenv"):
+		return "docker"
+	case p.exists("run/.containerenv"):
+		return "podman"
+	}
+	return ""
+}
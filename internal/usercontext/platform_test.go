@@ -0,0 +1,190 @@
+package usercontext
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+const (
+	ubuntuRelease = `PRETTY_NAME="Ubuntu 24.04 LTS"
+NAME="Ubuntu"
+VERSION_ID="24.04"
+ID=ubuntu
+ID_LIKE=debian
+`
+	rockyRelease = `NAME="Rocky Linux"
+VERSION_ID="7.9"
+ID="rocky"
+ID_LIKE="rhel centos fedora"
+PRETTY_NAME="Rocky Linux 7.9"
+`
+	alpineRelease = `NAME="Alpine Linux"
+ID=alpine
+VERSION_ID=3.20.0
+PRETTY_NAME="Alpine Linux v3.20"
+`
+	tumbleweedRelease = `NAME="openSUSE Tumbleweed"
+ID="opensuse-tumbleweed"
+ID_LIKE="opensuse suse"
+PRETTY_NAME="openSUSE Tumbleweed"
+`
+	darwinPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>ProductName</key>
+	<string>macOS</string>
+	<key>ProductVersion</key>
+	<string>14.5</string>
+</dict>
+</plist>
+`
+)
+
+// fakePath returns a lookPath that only finds cmds.
+func fakePath(cmds ...string) func(string) (string, error) {
+	return func(cmd string) (string, error) {
+		if slices.Contains(cmds, cmd) {
+			return "/usr/bin/" + cmd, nil
+		}
+		return "", errors.New("not found")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name  string
+		goos  string
+		files fstest.MapFS
+		path  []string
+		want  SystemContext
+		desc  string
+	}{
+		{
+			name: "ubuntu with snap and docker",
+			goos: "linux",
+			files: fstest.MapFS{
+				"etc/os-release":     {Data: []byte(ubuntuRelease)},
+				"run/systemd/system": {Mode: fs.ModeDir | 0o755},
+			},
+			path: []string{"apt", "snap", "flatpak", "docker", "podman"},
+			want: SystemContext{
+				OS:               "linux",
+				Distro:           "Ubuntu 24.04 LTS",
+				DistroID:         "ubuntu",
+				DistroLike:       []string{"debian"},
+				DistroVersion:    "24.04",
+				PackageMgr:       "apt",
+				ExtraPackageMgrs: []string{"snap", "flatpak"},
+				InitSystem:       "systemd",
+				ContainerRuntime: "docker",
+			},
+			desc: "OS: linux, Distro: Ubuntu 24.04 LTS (ubuntu 24.04, Debian-like), Shell: bash, Package manager: apt (also snap, flatpak), Init: systemd, Container runtime: docker",
+		},
+		{
+			name: "old RHEL-like only has yum",
+			goos: "linux",
+			files: fstest.MapFS{
+				"usr/lib/os-release": {Data: []byte(rockyRelease)},
+				"proc/1/comm":        {Data: []byte("systemd\n")},
+			},
+			path: []string{"yum", "podman"},
+			want: SystemContext{
+				OS:               "linux",
+				Distro:           "Rocky Linux 7.9",
+				DistroID:         "rocky",
+				DistroLike:       []string{"rhel", "centos", "fedora"},
+				DistroVersion:    "7.9",
+				PackageMgr:       "yum",
+				InitSystem:       "systemd",
+				ContainerRuntime: "podman",
+			},
+			desc: "OS: linux, Distro: Rocky Linux 7.9 (rocky 7.9, RHEL/CentOS/Fedora-like), Shell: bash, Package manager: yum, Init: systemd, Container runtime: podman",
+		},
+		{
+			name: "alpine container with openrc",
+			goos: "linux",
+			files: fstest.MapFS{
+				"etc/os-release": {Data: []byte(alpineRelease)},
+				"sbin/openrc":    {},
+				".dockerenv":     {},
+			},
+			path: []string{"apk"},
+			want: SystemContext{
+				OS:            "linux",
+				Distro:        "Alpine Linux v3.20",
+				DistroID:      "alpine",
+				DistroVersion: "3.20.0",
+				PackageMgr:    "apk",
+				InitSystem:    "openrc",
+				Container:     "docker",
+			},
+			desc: "OS: linux, Distro: Alpine Linux v3.20 (alpine 3.20.0), Shell: bash, Package manager: apk, Init: openrc, Running inside a docker container",
+		},
+		{
+			name: "distro found through ID_LIKE",
+			goos: "linux",
+			files: fstest.MapFS{
+				"etc/os-release": {Data: []byte(tumbleweedRelease)},
+			},
+			path: []string{"zypper", "flatpak", "nix-env"},
+			want: SystemContext{
+				OS:               "linux",
+				Distro:           "openSUSE Tumbleweed",
+				DistroID:         "opensuse-tumbleweed",
+				DistroLike:       []string{"opensuse", "suse"},
+				PackageMgr:       "zypper",
+				ExtraPackageMgrs: []string{"flatpak", "nix"},
+				InitSystem:       "unknown",
+			},
+			desc: "OS: linux, Distro: openSUSE Tumbleweed (opensuse-tumbleweed, openSUSE/SUSE-like), Shell: bash, Package manager: zypper (also flatpak, nix)",
+		},
+		{
+			name:  "no os-release falls back to PATH",
+			goos:  "linux",
+			files: fstest.MapFS{},
+			path:  []string{"xbps-install"},
+			want: SystemContext{
+				OS:         "linux",
+				Distro:     "linux (unknown)",
+				PackageMgr: "xbps",
+				InitSystem: "unknown",
+			},
+			desc: "OS: linux, Distro: linux (unknown), Shell: bash, Package manager: xbps",
+		},
+		{
+			name: "macOS",
+			goos: "darwin",
+			files: fstest.MapFS{
+				"System/Library/CoreServices/SystemVersion.plist": {Data: []byte(darwinPlist)},
+			},
+			path: []string{"brew", "docker"},
+			want: SystemContext{
+				OS:               "darwin",
+				Distro:           "macOS 14.5",
+				PackageMgr:       "brew",
+				InitSystem:       "launchd",
+				ContainerRuntime: "docker",
+			},
+			desc: "OS: darwin, Distro: macOS 14.5, Shell: bash, Package manager: brew, Init: launchd, Container runtime: docker",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detect(platform{goos: tt.goos, fsys: tt.files, lookPath: fakePath(tt.path...)})
+			got.Custom = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("detect() = %+v, want %+v", got, tt.want)
+			}
+
+			got.Shell = "bash"
+			if desc := got.Describe(); desc != tt.desc {
+				t.Errorf("Describe() =\n%q\nwant\n%q", desc, tt.desc)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package usercontext
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// maxListed caps how many scripts, targets or services are named per tool.
+const maxListed = 15
+
+// Toolchain is one kind of project found from the working directory, like a
+// Go module or a Makefile.
+type Toolchain struct {
+	Name    string   // "Go", "Node", "Python", "Rust", "Make" or "Docker Compose"
+	File    string   // Marker file, relative to the working directory
+	Details []string // Module name, package manager, ...
+	Tasks   []string // npm scripts, make targets or compose services
+}
+
+func (t Toolchain) String() string {
+	s := t.Name + " (" + t.File
+	if len(t.Details) > 0 {
+		s += ", " + strings.Join(t.Details, ", ")
+	}
+	s += ")"
+	if len(t.Tasks) > 0 {
+		label := map[string]string{"Node": "scripts", "Make": "targets", "Docker Compose": "services"}[t.Name]
+		s += " " + label + ": " + strings.Join(t.Tasks, ", ")
+	}
+	return s
+}
+
+// Project lists the toolchains of the project the user is working in.
+type Project struct {
+	Toolchains []Toolchain
+}
+
+// String summarizes the project for the prompt.
+func (p *Project) String() string {
+	parts := make([]string, len(p.Toolchains))
+	for i, t := range p.Toolchains {
+		parts[i] = t.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// marker is a file that gives a toolchain away, and how to read it.
+type marker struct {
+	names []string
+	read  func(fsys fs.FS, dir, name string) Toolchain
+}
+
+var markers = []marker{
+	{[]string{"go.mod"}, readGoMod},
+	{[]string{"package.json"}, readPackageJSON},
+	{[]string{"pyproject.toml", "setup.py", "requirements.txt"}, readPython},
+	{[]string{"Cargo.toml"}, readCargo},
+	{[]string{"GNUmakefile", "Makefile", "makefile"}, readMakefile},
+	{[]string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}, readCompose},
+}
+
+// getProjectInfo looks for marker files in dir and its parents, or returns
+// nil if there are none.
+func getProjectInfo(dir string) *Project {
+	root := filepath.VolumeName(dir) + string(filepath.Separator)
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return nil
+	}
+	return detectProject(os.DirFS(root), filepath.ToSlash(rel))
+}
+
+// detectProject walks up from dir, which is relative to the root of fsys,
+// and keeps the nearest file of each kind. It stops at the root of the git
+// repository, if there is one, so unrelated files further up stay out.
+func detectProject(fsys fs.FS, dir string) *Project {
+	found := make([]bool, len(markers))
+	var project Project
+	for cur := dir; ; cur = path.Dir(cur) {
+		for i, m := range markers {
+			if found[i] {
+				continue
+			}
+			for _, name := range m.names {
+				if _, err := fs.Stat(fsys, path.Join(cur, name)); err != nil {
+					continue
+				}
+				t := m.read(fsys, cur, name)
+				t.File = relativeTo(dir, path.Join(cur, name))
+				project.Toolchains = append(project.Toolchains, t)
+				found[i] = true
+				break
+			}
+		}
+		if _, err := fs.Stat(fsys, path.Join(cur, ".git")); err == nil || cur == "." {
+			break
+		}
+	}
+	if len(project.Toolchains) == 0 {
+		return nil
+	}
+	return &project
+}
+
+// relativeTo returns name as seen from dir, e.g. "../Makefile".
+func relativeTo(dir, name string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(name))
+	if err != nil {
+		return name
+	}
+	return filepath.ToSlash(rel)
+}
+
+func readGoMod(fsys fs.FS, dir, name string) Toolchain {
+	t := Toolchain{Name: "Go"}
+	data, err := fs.ReadFile(fsys, path.Join(dir, name))
+	if err != nil {
+		return t
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "module":
+			t.Details = append(t.Details, "module "+strings.Trim(fields[1], `"`))
+		case "go":
+			t.Details = append(t.Details, "go "+fields[1])
+		}
+	}
+	return t
+}
+
+func readPackageJSON(fsys fs.FS, dir, name string) Toolchain {
+	t := Toolchain{Name: "Node"}
+	var pkg struct {
+		Name           string            `json:"name"`
+		PackageManager string            `json:"packageManager"`
+		Scripts        map[string]string `json:"scripts"`
+	}
+	if data, err := fs.ReadFile(fsys, path.Join(dir, name)); err == nil {
+		json.Unmarshal(data, &pkg)
+	}
+	if pkg.Name != "" {
+		t.Details = append(t.Details, "package "+pkg.Name)
+	}
+
+	manager, _, _ := strings.Cut(pkg.PackageManager, "@")
+	if manager == "" {
+		for _, lock := range []struct{ file, manager string }{
+			{"pnpm-lock.yaml", "pnpm"},
+			{"yarn.lock", "yarn"},
+			{"bun.lockb", "bun"},
+			{"bun.lock", "bun"},
+			{"package-lock.json", "npm"},
+		} {
+			if _, err := fs.Stat(fsys, path.Join(dir, lock.file)); err == nil {
+				manager = lock.manager
+				break
+			}
+		}
+	}
+	if manager != "" {
+		t.Details = append(t.Details, manager)
+	}
+
+	for script := range pkg.Scripts {
+		t.Tasks = append(t.Tasks, script)
+	}
+	slices.Sort(t.Tasks)
+	t.Tasks = t.Tasks[:min(len(t.Tasks), maxListed)]
+	return t
+}
+
+func readPython(fsys fs.FS, dir, name string) Toolchain {
+	t := Toolchain{Name: "Python"}
+	if name == "pyproject.toml" {
+		data, _ := fs.ReadFile(fsys, path.Join(dir, name))
+		section := ""
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "[") {
+				section = strings.Trim(line, "[] ")
+				continue
+			}
+			key, value, ok := strings.Cut(line, "=")
+			if ok && strings.TrimSpace(key) == "name" && (section == "project" || section == "tool.poetry") {
+				t.Details = append(t.Details, "package "+strings.Trim(strings.TrimSpace(value), `"'`))
+			}
+		}
+		for _, tool := range []string{"poetry", "uv", "pdm", "hatch"} {
+			if strings.Contains(string(data), "[tool."+tool) {
+				t.Details = append(t.Details, tool)
+				break
+			}
+		}
+	}
+	for _, lock := range []struct{ file, tool string }{
+		{"uv.lock", "uv"},
+		{"poetry.lock", "poetry"},
+		{"Pipfile.lock", "pipenv"},
+	} {
+		if _, err := fs.Stat(fsys, path.Join(dir, lock.file)); err == nil && !slices.Contains(t.Details, lock.tool) {
+			t.Details = append(t.Details, lock.tool)
+		}
+	}
+	return t
+}
+
+func readCargo(fsys fs.FS, dir, name string) Toolchain {
+	t := Toolchain{Name: "Rust"}
+	data, _ := fs.ReadFile(fsys, path.Join(dir, name))
+	section := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			section = strings.Trim(line, "[] ")
+			if section == "workspace" {
+				t.Details = append(t.Details, "workspace")
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok && section == "package" && strings.TrimSpace(key) == "name" {
+			t.Details = append(t.Details, "crate "+strings.Trim(strings.TrimSpace(value), `"'`))
+		}
+	}
+	return t
+}
+
+// makeTargetRe matches rule lines, but not variable assignments like "CC := gcc".
+var makeTargetRe = regexp.MustCompile(`^([A-Za-z0-9_./-]+(?:\s+[A-Za-z0-9_./-]+)*)\s*:([^=:]|$)`)
+
+func readMakefile(fsys fs.FS, dir, name string) Toolchain {
+	t := Toolchain{Name: "Make"}
+	f, err := fsys.Open(path.Join(dir, name))
+	if err != nil {
+		return t
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() && len(t.Tasks) < maxListed {
+		m := makeTargetRe.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		for _, target := range strings.Fields(m[1]) {
+			// Special targets like .PHONY and file targets aren't things to run
+			if strings.HasPrefix(target, ".") || strings.Contains(target, "/") || slices.Contains(t.Tasks, target) {
+				continue
+			}
+			t.Tasks = append(t.Tasks, target)
+		}
+	}
+	t.Tasks = t.Tasks[:min(len(t.Tasks), maxListed)]
+	return t
+}
+
+func readCompose(fsys fs.FS, dir, name string) Toolchain {
+	t := Toolchain{Name: "Docker Compose"}
+	data, err := fs.ReadFile(fsys, path.Join(dir, name))
+	if err != nil {
+		return t
+	}
+	var compose yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&compose); err != nil || len(compose.Content) == 0 {
+		return t
+	}
+	// Walk the nodes rather than decoding into a map to keep the file's order
+	top := compose.Content[0]
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		if top.Content[i].Value != "services" {
+			continue
+		}
+		services := top.Content[i+1]
+		for j := 0; j+1 < len(services.Content) && len(t.Tasks) < maxListed; j += 2 {
+			t.Tasks = append(t.Tasks, services.Content[j].Value)
+		}
+	}
+	return t
+}
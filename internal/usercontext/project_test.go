@@ -0,0 +1,115 @@
+package usercontext
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDetectProject(t *testing.T) {
+	files := fstest.MapFS{
+		// Outside the repository, must not be picked up
+		"home/me/Makefile": {Data: []byte("all:\n")},
+
+		"home/me/shop/.git/HEAD": {Data: []byte("ref: refs/heads/main\n")},
+		"home/me/shop/Makefile": {Data: []byte(`CC := gcc
+VERSION ?= 1.0
+.PHONY: build test
+build: deps
+	go build ./...
+test lint:
+	go test ./...
+bin/shop: build
+%.o: %.c
+`)},
+		"home/me/shop/compose.yaml": {Data: []byte(`services:
+  web:
+    build: .
+  db:
+    image: postgres
+volumes:
+  data:
+`)},
+		"home/me/shop/go.mod": {Data: []byte("module example.com/shop\n\ngo 1.24\n")},
+		"home/me/shop/web/package.json": {Data: []byte(`{
+  "name": "shop-web",
+  "scripts": {"test": "vitest", "build": "vite build", "dev": "vite"}
+}`)},
+		"home/me/shop/web/pnpm-lock.yaml": {Data: []byte("lockfileVersion: 9\n")},
+		"home/me/shop/web/src/app.ts":     {Data: []byte("")},
+		"home/me/shop/ml/pyproject.toml": {Data: []byte(`[project]
+name = "shop-ml"
+
+[tool.uv]
+dev-dependencies = []
+`)},
+		"home/me/shop/engine/Cargo.toml": {Data: []byte(`[package]
+name = "engine"
+version = "0.1.0"
+`)},
+	}
+
+	tests := []struct {
+		dir  string
+		want []Toolchain
+	}{
+		{
+			dir: "home/me/shop/web/src",
+			want: []Toolchain{
+				{Name: "Node", File: "../package.json", Details: []string{"package shop-web", "pnpm"}, Tasks: []string{"build", "dev", "test"}},
+				{Name: "Go", File: "../../go.mod", Details: []string{"module example.com/shop", "go 1.24"}},
+				{Name: "Make", File: "../../Makefile", Tasks: []string{"build", "test", "lint"}},
+				{Name: "Docker Compose", File: "../../compose.yaml", Tasks: []string{"web", "db"}},
+			},
+		},
+		{
+			dir: "home/me/shop/ml",
+			want: []Toolchain{
+				{Name: "Python", File: "pyproject.toml", Details: []string{"package shop-ml", "uv"}},
+				{Name: "Go", File: "../go.mod", Details: []string{"module example.com/shop", "go 1.24"}},
+				{Name: "Make", File: "../Makefile", Tasks: []string{"build", "test", "lint"}},
+				{Name: "Docker Compose", File: "../compose.yaml", Tasks: []string{"web", "db"}},
+			},
+		},
+		{
+			dir: "home/me/shop/engine",
+			want: []Toolchain{
+				{Name: "Rust", File: "Cargo.toml", Details: []string{"crate engine"}},
+				{Name: "Go", File: "../go.mod", Details: []string{"module example.com/shop", "go 1.24"}},
+				{Name: "Make", File: "../Makefile", Tasks: []string{"build", "test", "lint"}},
+				{Name: "Docker Compose", File: "../compose.yaml", Tasks: []string{"web", "db"}},
+			},
+		},
+		{
+			dir:  "tmp",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			got := detectProject(files, tt.dir)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("detectProject() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || !reflect.DeepEqual(got.Toolchains, tt.want) {
+				t.Errorf("detectProject() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectString(t *testing.T) {
+	p := &Project{Toolchains: []Toolchain{
+		{Name: "Node", File: "package.json", Details: []string{"npm"}, Tasks: []string{"build", "test"}},
+		{Name: "Go", File: "../go.mod", Details: []string{"module example.com/shop"}},
+		{Name: "Make", File: "Makefile", Tasks: []string{"all"}},
+	}}
+	want := "Node (package.json, npm) scripts: build, test; Go (../go.mod, module example.com/shop); Make (Makefile) targets: all"
+	if got := p.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}